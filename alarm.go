@@ -0,0 +1,173 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+const (
+	defaultAlarmWatchInterval = 15 * time.Second
+	maxAlarmWatchNames        = 100 // limit of DescribeAlarms API
+)
+
+// ConfigAlarmWatch represents CloudWatch alarms to be watched during the rollout and the bake time of deployments.
+type ConfigAlarmWatch struct {
+	AlarmNames []string  `yaml:"alarm_names" json:"alarm_names"`
+	BakeTime   *Duration `yaml:"bake_time,omitempty" json:"bake_time,omitempty"`
+	Interval   *Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+}
+
+func (c *ConfigAlarmWatch) setup() error {
+	if c == nil {
+		return nil
+	}
+	if len(c.AlarmNames) == 0 {
+		return fmt.Errorf("alarm_watch: alarm_names is required")
+	}
+	if len(c.AlarmNames) > maxAlarmWatchNames {
+		return fmt.Errorf("alarm_watch: too many alarm_names (max %d)", maxAlarmWatchNames)
+	}
+	if c.BakeTime == nil {
+		c.BakeTime = &Duration{}
+	}
+	if c.Interval == nil {
+		c.Interval = &Duration{Duration: defaultAlarmWatchInterval}
+	}
+	return nil
+}
+
+// ErrAlarm represents that watched alarms are in ALARM state.
+type ErrAlarm struct {
+	Alarms []string
+}
+
+func (e ErrAlarm) Error() string {
+	return fmt.Sprintf("alarms are in ALARM state: %s", strings.Join(e.Alarms, ","))
+}
+
+// firingAlarms returns names of the watched alarms in ALARM state.
+func (d *App) firingAlarms(ctx context.Context, client *cloudwatch.Client) ([]string, error) {
+	c := d.config.AlarmWatch
+	out, err := client.DescribeAlarms(ctx, &cloudwatch.DescribeAlarmsInput{
+		AlarmNames: c.AlarmNames,
+		AlarmTypes: []cwTypes.AlarmType{cwTypes.AlarmTypeMetricAlarm, cwTypes.AlarmTypeCompositeAlarm},
+		StateValue: cwTypes.StateValueAlarm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe alarms: %w", err)
+	}
+	var names []string
+	for _, a := range out.MetricAlarms {
+		names = append(names, aws.ToString(a.AlarmName))
+	}
+	for _, a := range out.CompositeAlarms {
+		names = append(names, aws.ToString(a.AlarmName))
+	}
+	return names, nil
+}
+
+// checkAlarms returns ErrAlarm when any alarm is already in ALARM state.
+func (d *App) checkAlarms(ctx context.Context) error {
+	names, err := d.firingAlarms(ctx, cloudwatch.NewFromConfig(d.config.awsv2Config))
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("unable to deploy before the alarms recover: %w", ErrAlarm{Alarms: names})
+	}
+	return nil
+}
+
+// watchAlarms polls the alarms until ctx is done. It returns ErrAlarm when any alarm is in ALARM state.
+// Failures of polling are logged as warnings.
+func (d *App) watchAlarms(ctx context.Context) error {
+	c := d.config.AlarmWatch
+	client := cloudwatch.NewFromConfig(d.config.awsv2Config)
+	ticker := time.NewTicker(c.Interval.Duration)
+	defer ticker.Stop()
+	for {
+		names, err := d.firingAlarms(ctx, client)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			d.Log("[WARNING] %s", err)
+		} else if len(names) > 0 {
+			return ErrAlarm{Alarms: names}
+		} else {
+			d.Log("[DEBUG] no alarms are in ALARM state")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// alarmWatchWaitFunc returns a waitFunc which watches the alarms during the rollout and the bake time.
+// When any alarm goes into ALARM state, the service is rolled back to rollbackTdArn.
+func (d *App) alarmWatchWaitFunc(doWait waitFunc, rollbackTdArn string) waitFunc {
+	return func(ctx context.Context, sv *Service) error {
+		c := d.config.AlarmWatch
+		d.Log("Watching alarms %s", strings.Join(c.AlarmNames, ","))
+		watchCtx, cancelWatch := context.WithCancel(ctx)
+		defer cancelWatch()
+		alarmCh := make(chan error, 1)
+		go func() { alarmCh <- d.watchAlarms(watchCtx) }()
+
+		waitCtx, cancelWait := context.WithCancel(ctx)
+		defer cancelWait()
+		waitCh := make(chan error, 1)
+		go func() { waitCh <- doWait(waitCtx, sv) }()
+
+		select {
+		case err := <-alarmCh:
+			cancelWait()
+			<-waitCh
+			return d.rollbackByAlarm(ctx, rollbackTdArn, err)
+		case err := <-waitCh:
+			if err != nil {
+				return err
+			}
+		}
+
+		if c.BakeTime.Duration > 0 {
+			d.Log("Watching alarms for bake time %s", c.BakeTime.Duration)
+			select {
+			case err := <-alarmCh:
+				return d.rollbackByAlarm(ctx, rollbackTdArn, err)
+			case <-time.After(c.BakeTime.Duration):
+			}
+		}
+		d.Log("No alarms went into ALARM state")
+		return nil
+	}
+}
+
+// rollbackByAlarm rolls back the service to rollbackTdArn as the rollback command does.
+func (d *App) rollbackByAlarm(ctx context.Context, rollbackTdArn string, err error) error {
+	if err == nil {
+		// watching alarms is canceled
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("watching alarms is stopped unexpectedly")
+	}
+	d.Log("[WARNING] %s", err)
+	d.Log("Rolling back the service to %s", arnToName(rollbackTdArn))
+	// the target is pinned, because another task definition may be registered during the deployment
+	ropt := RollbackOption{DeregisterTaskDefinition: true, Wait: true, targetArn: rollbackTdArn}
+	if rerr := d.audit(ctx, "rollback", false, func(ctx context.Context) error {
+		return d.rollback(ctx, ropt)
+	}); rerr != nil {
+		return fmt.Errorf("%s, and failed to roll back: %w", err, rerr)
+	}
+	return fmt.Errorf("the service was rolled back to %s: %w", arnToName(rollbackTdArn), err)
+}
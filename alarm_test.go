@@ -0,0 +1,188 @@
+package ecspresso_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/kayac/ecspresso/v2"
+)
+
+// newAlarmTestApp returns an App whose DescribeAlarms returns no alarms until fireAt calls, and returns an alarm in ALARM state after that.
+func newAlarmTestApp(t *testing.T, fireAt int32) (*ecspresso.App, *sdkMock) {
+	var n int32
+	return newSDKMockApp(t, "tests/alarm/ecspresso.yml", map[string]any{
+		"DescribeAlarms": func(any) (any, error) {
+			out := &cloudwatch.DescribeAlarmsOutput{}
+			if n := atomic.AddInt32(&n, 1); fireAt > 0 && n >= fireAt {
+				out.MetricAlarms = []cwTypes.MetricAlarm{
+					{AlarmName: ptr("test-5xx"), StateValue: cwTypes.StateValueAlarm},
+				}
+			}
+			return out, nil
+		},
+	})
+}
+
+func TestWatchAlarms(t *testing.T) {
+	app, m := newAlarmTestApp(t, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := app.WatchAlarms(ctx)
+	var errAlarm ecspresso.ErrAlarm
+	if !errors.As(err, &errAlarm) {
+		t.Fatalf("expected ErrAlarm, got %v", err)
+	}
+	if len(errAlarm.Alarms) != 1 || errAlarm.Alarms[0] != "test-5xx" {
+		t.Errorf("unexpected alarms %v", errAlarm.Alarms)
+	}
+	if n := m.count("DescribeAlarms"); n != 3 {
+		t.Errorf("unexpected number of calls %d", n)
+	}
+}
+
+func TestAlarmWatchWaitBakeTime(t *testing.T) {
+	app, m := newAlarmTestApp(t, 0)
+	ctx := context.Background()
+
+	start := time.Now()
+	err := app.AlarmWatchWait(ctx, "", func(context.Context, *ecspresso.Service) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("bake time is not waited: %s", elapsed)
+	}
+	if m.count("DescribeAlarms") < 2 {
+		t.Errorf("alarms are not polled")
+	}
+
+	// errors of waiting are returned as is
+	waitErr := errors.New("service is not stable")
+	if err := app.AlarmWatchWait(ctx, "", func(context.Context, *ecspresso.Service) error {
+		return waitErr
+	}); !errors.Is(err, waitErr) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDeployWithAlarmInAlarmState(t *testing.T) {
+	app, m := newSDKMockApp(t, "tests/alarm/ecspresso.yml", map[string]any{
+		"DescribeServices": &ecs.DescribeServicesOutput{
+			Services: []types.Service{
+				{
+					ServiceName:    ptr("test"),
+					ClusterArn:     ptr("arn:aws:ecs:ap-northeast-1:123456789012:cluster/default"),
+					Status:         ptr("ACTIVE"),
+					TaskDefinition: ptr("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:1"),
+				},
+			},
+		},
+		"DescribeScalableTargets": &applicationautoscaling.DescribeScalableTargetsOutput{},
+		"DescribeAlarms": &cloudwatch.DescribeAlarmsOutput{
+			MetricAlarms: []cwTypes.MetricAlarm{{AlarmName: ptr("test-5xx"), StateValue: cwTypes.StateValueAlarm}},
+		},
+		"UpdateService": &ecs.UpdateServiceOutput{Service: &types.Service{}},
+	})
+	err := app.Deploy(context.Background(), ecspresso.DeployOption{
+		SkipTaskDefinition: true,
+		UpdateService:      true,
+		Wait:               true,
+	})
+	var errAlarm ecspresso.ErrAlarm
+	if !errors.As(err, &errAlarm) {
+		t.Fatalf("expected ErrAlarm, got %v", err)
+	}
+	if n := m.count("UpdateService"); n != 0 {
+		t.Errorf("UpdateService must not be called when the alarms are in ALARM state: %d calls", n)
+	}
+}
+
+func TestRollbackByAlarm(t *testing.T) {
+	defer ecspresso.SetDelayForServiceChanged(0)()
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Setenv("AUDIT_PATH", auditPath)
+	const (
+		tdArn1 = "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:1"
+		tdArn2 = "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:2"
+	)
+	var rolledBack atomic.Bool
+	service := func(any) (any, error) {
+		td := tdArn2
+		if rolledBack.Load() {
+			td = tdArn1
+		}
+		return &ecs.DescribeServicesOutput{
+			Services: []types.Service{
+				{
+					ServiceName:          ptr("test"),
+					ClusterArn:           ptr("arn:aws:ecs:ap-northeast-1:123456789012:cluster/default"),
+					Status:               ptr("ACTIVE"),
+					TaskDefinition:       ptr(td),
+					DeploymentController: &types.DeploymentController{Type: types.DeploymentControllerTypeEcs},
+					DesiredCount:         1,
+					RunningCount:         1,
+					Deployments: []types.Deployment{
+						{Id: ptr("ecs-svc/1"), Status: ptr("PRIMARY"), TaskDefinition: ptr(td), DesiredCount: 1, RunningCount: 1},
+					},
+				},
+			},
+		}, nil
+	}
+	app, m := newSDKMockApp(t, "tests/alarm/rollback.yml", map[string]any{
+		"DescribeAlarms": &cloudwatch.DescribeAlarmsOutput{
+			MetricAlarms: []cwTypes.MetricAlarm{{AlarmName: ptr("test-5xx"), StateValue: cwTypes.StateValueAlarm}},
+		},
+		"DescribeServices":        service,
+		"DescribeScalableTargets": &applicationautoscaling.DescribeScalableTargetsOutput{},
+		"UpdateService": func(any) (any, error) {
+			rolledBack.Store(true)
+			return &ecs.UpdateServiceOutput{Service: &types.Service{}}, nil
+		},
+		"DeregisterTaskDefinition": &ecs.DeregisterTaskDefinitionOutput{},
+		"GetCallerIdentity":        &sts.GetCallerIdentityOutput{Arn: ptr("arn:aws:iam::123456789012:user/test")},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := app.AlarmWatchWait(ctx, tdArn1, func(ctx context.Context, _ *ecspresso.Service) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	var errAlarm ecspresso.ErrAlarm
+	if !errors.As(err, &errAlarm) || !strings.Contains(err.Error(), "rolled back to test:1") {
+		t.Fatalf("expected the service rolled back by ErrAlarm, got %v", err)
+	}
+	// rolled back to the pinned task definition without finding the target
+	if n := m.count("ListTaskDefinitions"); n != 0 {
+		t.Errorf("the rollback target must not be found: %d calls", n)
+	}
+	if in, ok := m.called("UpdateService"); !ok || *in.(*ecs.UpdateServiceInput).TaskDefinition != tdArn1 {
+		t.Errorf("unexpected UpdateService input %#v", in)
+	}
+	if in, ok := m.called("DeregisterTaskDefinition"); !ok || *in.(*ecs.DeregisterTaskDefinitionInput).TaskDefinition != tdArn2 {
+		t.Errorf("unexpected DeregisterTaskDefinition input %#v", in)
+	}
+	// recorded as a rollback command
+	rs, err := ecspresso.ReadAuditRecordsFromFile(auditPath, "default", "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || rs[0].Command != "rollback" || rs[0].Outcome != "success" || rs[0].TaskDefinition != tdArn1 {
+		t.Errorf("unexpected audit records %#v", rs)
+	}
+}
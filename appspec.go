@@ -2,15 +2,23 @@ package ecspresso
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/kayac/ecspresso/v2/appspec"
 )
 
 type AppSpecOption struct {
 	TaskDefinition string `help:"use task definition arn in AppSpec (latest, current or Arn)" default:"latest"`
 	UpdateService  bool   `help:"update service definition with task definition arn" default:"true" negatable:""`
+	Format         string `help:"output format" default:"yaml" enum:"yaml,json"`
+	S3             string `name:"s3" help:"write AppSpec to S3 (s3://bucket/key) instead of STDOUT" default:""`
 }
 
 func (d *App) AppSpec(ctx context.Context, opt AppSpecOption) error {
@@ -52,6 +60,64 @@ func (d *App) AppSpec(ctx context.Context, opt AppSpecOption) error {
 		spec.Hooks = d.config.AppSpec.Hooks
 	}
 
-	fmt.Print(spec.String())
+	var content string
+	switch opt.Format {
+	case "json":
+		content = spec.JSON()
+	default:
+		content = spec.String()
+	}
+	if opt.S3 != "" {
+		return d.putAppSpecToS3(ctx, opt.S3, content)
+	}
+	fmt.Print(content)
+	return nil
+}
+
+func (d *App) putAppSpecToS3(ctx context.Context, s3URL string, content string) error {
+	u, err := url.Parse(s3URL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" || strings.TrimPrefix(u.Path, "/") == "" {
+		return fmt.Errorf("--s3 requires s3://bucket/key: %s", s3URL)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if _, err := s3.NewFromConfig(d.config.awsv2Config).PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   strings.NewReader(content),
+	}); err != nil {
+		return fmt.Errorf("failed to put AppSpec to %s: %w", s3URL, err)
+	}
+	d.Log("AppSpec is written to %s", s3URL)
+	return nil
+}
+
+// verifyAppSpec verifies Lambda functions of the AppSpec hooks exist.
+func (d *App) verifyAppSpec(ctx context.Context) error {
+	var targets []verifyTarget
+	for _, h := range d.config.AppSpec.Hooks {
+		for _, e := range h.Events() {
+			e := e
+			targets = append(targets, verifyTarget{
+				name: fmt.Sprintf("%s[%s]", e.Name, e.Function),
+				fn: func(ctx context.Context) error {
+					return d.verifyLambdaFunction(ctx, e.Function)
+				},
+			})
+		}
+	}
+	return verifyResources(ctx, targets).orNil()
+}
+
+func (d *App) verifyLambdaFunction(ctx context.Context, name string) error {
+	_, err := lambda.NewFromConfig(d.config.awsv2Config).GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		var notFound *lambdaTypes.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return ErrNotFound(fmt.Sprintf("lambda function %s is not found", name))
+		}
+		return fmt.Errorf("failed to get lambda function %s: %w", name, err)
+	}
 	return nil
 }
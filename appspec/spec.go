@@ -1,7 +1,10 @@
 package appspec
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
@@ -14,9 +17,9 @@ var (
 )
 
 type AppSpec struct {
-	Version   *string     `yaml:"version"`
-	Resources []*Resource `yaml:"Resources,omitempty"`
-	Hooks     []*Hook     `yaml:"Hooks,omitempty"`
+	Version   *string     `yaml:"version" json:"version"`
+	Resources []*Resource `yaml:"Resources,omitempty" json:"Resources,omitempty"`
+	Hooks     []*Hook     `yaml:"Hooks,omitempty" json:"Hooks,omitempty"`
 }
 
 func New() *AppSpec {
@@ -30,6 +33,25 @@ func (a *AppSpec) String() string {
 	return string(b)
 }
 
+// JSON returns the AppSpec in JSON format.
+func (a *AppSpec) JSON() string {
+	b, _ := json.MarshalIndent(a, "", "  ")
+	return string(b) + "\n"
+}
+
+// Validate validates hooks of the AppSpec.
+func (a *AppSpec) Validate() error {
+	for i, h := range a.Hooks {
+		if h == nil {
+			return fmt.Errorf("Hooks[%d] is empty", i)
+		}
+		if n := len(h.Events()); n != 1 {
+			return fmt.Errorf("Hooks[%d] must have exactly one hook, but has %d", i, n)
+		}
+	}
+	return nil
+}
+
 func Unmarsal(data []byte) (*AppSpec, error) {
 	var spec AppSpec
 	if err := yaml.Unmarshal(data, &spec); err != nil {
@@ -80,47 +102,115 @@ func NewWithService(sv *types.Service, tdArn string) (*AppSpec, error) {
 }
 
 type Resource struct {
-	TargetService *TargetService `yaml:"TargetService,omitempty"`
+	TargetService *TargetService `yaml:"TargetService,omitempty" json:"TargetService,omitempty"`
 }
 
 type TargetService struct {
-	Type       *string     `yaml:"Type,omitempty"`
-	Properties *Properties `yaml:"Properties,omitempty"`
+	Type       *string     `yaml:"Type,omitempty" json:"Type,omitempty"`
+	Properties *Properties `yaml:"Properties,omitempty" json:"Properties,omitempty"`
 }
 
 type Properties struct {
-	TaskDefinition           *string                     `yaml:"TaskDefinition,omitempty"`
-	LoadBalancerInfo         *LoadBalancerInfo           `yaml:"LoadBalancerInfo,omitempty"`
-	PlatformVersion          *string                     `yaml:"PlatformVersion,omitempty"`
-	NetworkConfiguration     *NetworkConfiguration       `yaml:"NetworkConfiguration,omitempty"`
-	CapacityProviderStrategy []*CapacityProviderStrategy `yaml:"CapacityProviderStrategy,omitempty"`
+	TaskDefinition           *string                     `yaml:"TaskDefinition,omitempty" json:"TaskDefinition,omitempty"`
+	LoadBalancerInfo         *LoadBalancerInfo           `yaml:"LoadBalancerInfo,omitempty" json:"LoadBalancerInfo,omitempty"`
+	PlatformVersion          *string                     `yaml:"PlatformVersion,omitempty" json:"PlatformVersion,omitempty"`
+	NetworkConfiguration     *NetworkConfiguration       `yaml:"NetworkConfiguration,omitempty" json:"NetworkConfiguration,omitempty"`
+	CapacityProviderStrategy []*CapacityProviderStrategy `yaml:"CapacityProviderStrategy,omitempty" json:"CapacityProviderStrategy,omitempty"`
 }
 
 type LoadBalancerInfo struct {
-	ContainerName *string `yaml:"ContainerName"`
-	ContainerPort *int32  `yaml:"ContainerPort"`
+	ContainerName *string `yaml:"ContainerName" json:"ContainerName"`
+	ContainerPort *int32  `yaml:"ContainerPort" json:"ContainerPort"`
 }
 
 type NetworkConfiguration struct {
-	AwsvpcConfiguration *AwsVpcConfiguration `yaml:"AwsvpcConfiguration,omitempty"`
+	AwsvpcConfiguration *AwsVpcConfiguration `yaml:"AwsvpcConfiguration,omitempty" json:"AwsvpcConfiguration,omitempty"`
 }
 
 type AwsVpcConfiguration struct {
-	AssignPublicIp types.AssignPublicIp `yaml:"AssignPublicIp,omitempty"`
-	SecurityGroups []string             `yaml:"SecurityGroups,omitempty"`
-	Subnets        []string             `yaml:"Subnets,omitempty"`
+	AssignPublicIp types.AssignPublicIp `yaml:"AssignPublicIp,omitempty" json:"AssignPublicIp,omitempty"`
+	SecurityGroups []string             `yaml:"SecurityGroups,omitempty" json:"SecurityGroups,omitempty"`
+	Subnets        []string             `yaml:"Subnets,omitempty" json:"Subnets,omitempty"`
 }
 
 type CapacityProviderStrategy struct {
-	CapacityProvider *string `yaml:"CapacityProvider,omitempty"`
-	Base             int32   `yaml:"Base,omitempty"`
-	Weight           int32   `yaml:"Weight,omitempty"`
+	CapacityProvider *string `yaml:"CapacityProvider,omitempty" json:"CapacityProvider,omitempty"`
+	Base             int32   `yaml:"Base,omitempty" json:"Base,omitempty"`
+	Weight           int32   `yaml:"Weight,omitempty" json:"Weight,omitempty"`
 }
 
 type Hook struct {
-	BeforeInstall         string `yaml:"BeforeInstall,omitempty"`
-	AfterInstall          string `yaml:"AfterInstall,omitempty"`
-	AfterAllowTestTraffic string `yaml:"AfterAllowTestTraffic,omitempty"`
-	BeforeAllowTraffic    string `yaml:"BeforeAllowTraffic,omitempty"`
-	AfterAllowTraffic     string `yaml:"AfterAllowTraffic,omitempty"`
+	BeforeInstall         string `yaml:"BeforeInstall,omitempty" json:"BeforeInstall,omitempty"`
+	AfterInstall          string `yaml:"AfterInstall,omitempty" json:"AfterInstall,omitempty"`
+	AfterAllowTestTraffic string `yaml:"AfterAllowTestTraffic,omitempty" json:"AfterAllowTestTraffic,omitempty"`
+	BeforeAllowTraffic    string `yaml:"BeforeAllowTraffic,omitempty" json:"BeforeAllowTraffic,omitempty"`
+	AfterAllowTraffic     string `yaml:"AfterAllowTraffic,omitempty" json:"AfterAllowTraffic,omitempty"`
+}
+
+// HookNames are names of the lifecycle event hooks for ECS deployments.
+var HookNames = []string{
+	"BeforeInstall",
+	"AfterInstall",
+	"AfterAllowTestTraffic",
+	"BeforeAllowTraffic",
+	"AfterAllowTraffic",
+}
+
+// HookEvent represents a pair of a lifecycle event and a Lambda function.
+type HookEvent struct {
+	Name     string
+	Function string
+}
+
+// Events returns the lifecycle events which have a Lambda function in the hook.
+func (h *Hook) Events() []HookEvent {
+	var events []HookEvent
+	for _, e := range []HookEvent{
+		{Name: "BeforeInstall", Function: h.BeforeInstall},
+		{Name: "AfterInstall", Function: h.AfterInstall},
+		{Name: "AfterAllowTestTraffic", Function: h.AfterAllowTestTraffic},
+		{Name: "BeforeAllowTraffic", Function: h.BeforeAllowTraffic},
+		{Name: "AfterAllowTraffic", Function: h.AfterAllowTraffic},
+	} {
+		if e.Function != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func (h *Hook) UnmarshalYAML(b []byte) error {
+	var m map[string]string
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return h.set(m)
+}
+
+func (h *Hook) UnmarshalJSON(b []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	return h.set(m)
+}
+
+func (h *Hook) set(m map[string]string) error {
+	for name, fn := range m {
+		switch name {
+		case "BeforeInstall":
+			h.BeforeInstall = fn
+		case "AfterInstall":
+			h.AfterInstall = fn
+		case "AfterAllowTestTraffic":
+			h.AfterAllowTestTraffic = fn
+		case "BeforeAllowTraffic":
+			h.BeforeAllowTraffic = fn
+		case "AfterAllowTraffic":
+			h.AfterAllowTraffic = fn
+		default:
+			return fmt.Errorf("invalid hook name %s. available hook names are %s", name, strings.Join(HookNames, ", "))
+		}
+	}
+	return nil
 }
@@ -1,6 +1,8 @@
 package appspec_test
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -80,3 +82,43 @@ func TestAppSpec(t *testing.T) {
 		t.Error("failed to Unmarsal", diff)
 	}
 }
+
+func TestAppSpecJSON(t *testing.T) {
+	var s appspec.AppSpec
+	if err := json.Unmarshal([]byte(expected.JSON()), &s); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&s, expected); diff != "" {
+		t.Error("failed to round trip JSON", diff)
+	}
+	if !strings.Contains(expected.JSON(), `"version": "0.0"`) {
+		t.Errorf("unexpected JSON %s", expected.JSON())
+	}
+}
+
+func TestAppSpecHooks(t *testing.T) {
+	if err := expected.Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	events := expected.Hooks[2].Events()
+	if len(events) != 1 || events[0].Name != "AfterAllowTestTraffic" || events[0].Function != "LambdaFunctionToValidateAfterTestTrafficStarts" {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	_, err := appspec.Unmarsal([]byte("Hooks:\n  - BeforeInstal: LambdaFunction\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid hook name BeforeInstal") {
+		t.Errorf("expected invalid hook name error, got %v", err)
+	}
+	var hooks []*appspec.Hook
+	if err := json.Unmarshal([]byte(`[{"AfterAllowTrafic":"LambdaFunction"}]`), &hooks); err == nil {
+		t.Error("expected invalid hook name error")
+	}
+
+	s, err := appspec.Unmarsal([]byte("Hooks:\n  - BeforeInstall: Foo\n    AfterInstall: Bar\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(); err == nil {
+		t.Error("expected error for a hook with multiple events")
+	}
+}
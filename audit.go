@@ -0,0 +1,520 @@
+package ecspresso
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwlTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	auditOutcomeSuccess = "success"
+	auditOutcomeFailure = "failure"
+)
+
+// ConfigAudit represents sinks of audit records. Records are written to all of the configured sinks.
+type ConfigAudit struct {
+	File           *ConfigAuditFile           `yaml:"file,omitempty" json:"file,omitempty"`
+	S3             *ConfigAuditS3             `yaml:"s3,omitempty" json:"s3,omitempty"`
+	CloudWatchLogs *ConfigAuditCloudWatchLogs `yaml:"cloudwatch_logs,omitempty" json:"cloudwatch_logs,omitempty"`
+}
+
+type ConfigAuditFile struct {
+	Path string `yaml:"path" json:"path"`
+}
+
+type ConfigAuditS3 struct {
+	Bucket    string `yaml:"bucket" json:"bucket"`
+	KeyPrefix string `yaml:"key_prefix,omitempty" json:"key_prefix,omitempty"`
+}
+
+type ConfigAuditCloudWatchLogs struct {
+	LogGroup  string `yaml:"log_group" json:"log_group"`
+	LogStream string `yaml:"log_stream,omitempty" json:"log_stream,omitempty"`
+}
+
+func (c *ConfigAudit) validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.File == nil && c.S3 == nil && c.CloudWatchLogs == nil {
+		return errors.New("audit requires at least one of file, s3 or cloudwatch_logs")
+	}
+	if c.File != nil && c.File.Path == "" {
+		return errors.New("audit.file.path is required")
+	}
+	if c.S3 != nil && c.S3.Bucket == "" {
+		return errors.New("audit.s3.bucket is required")
+	}
+	if c.CloudWatchLogs != nil && c.CloudWatchLogs.LogGroup == "" {
+		return errors.New("audit.cloudwatch_logs.log_group is required")
+	}
+	return nil
+}
+
+// AuditRecord represents a record of a mutating command.
+type AuditRecord struct {
+	Time                   time.Time `json:"time"`
+	Command                string    `json:"command"`
+	Cluster                string    `json:"cluster"`
+	Service                string    `json:"service"`
+	Caller                 string    `json:"caller"`
+	GitCommit              string    `json:"gitCommit,omitempty"`
+	TaskDefinition         string    `json:"taskDefinition,omitempty"`
+	PreviousTaskDefinition string    `json:"previousTaskDefinition,omitempty"`
+	DiffSummary            []string  `json:"diffSummary,omitempty"`
+	Outcome                string    `json:"outcome"`
+	Error                  string    `json:"error,omitempty"`
+	Duration               string    `json:"duration"`
+}
+
+func (r *AuditRecord) setTaskDefinition(previous, current string) {
+	if r == nil {
+		return
+	}
+	r.PreviousTaskDefinition = previous
+	r.TaskDefinition = current
+}
+
+func (r *AuditRecord) addDiffSummary(changes []DiffChange) {
+	if r == nil {
+		return
+	}
+	for _, c := range changes {
+		r.DiffSummary = append(r.DiffSummary, fmt.Sprintf("%s %s.%s", c.Type, c.Resource, c.Path))
+	}
+}
+
+// addTaskDefinitionDiffSummary adds the changes of the task definition from previous to current to the diff summary.
+// Failures are logged as warnings because the summary is informational.
+func (d *App) addTaskDefinitionDiffSummary(ctx context.Context, r *AuditRecord, previous, current string) {
+	if r == nil || previous == "" || current == "" || previous == current {
+		return
+	}
+	prevTd, err := d.DescribeTaskDefinition(ctx, previous)
+	if err != nil {
+		d.Log("[WARNING] failed to describe task definition for the audit record: %s", err)
+		return
+	}
+	currentTd, err := d.DescribeTaskDefinition(ctx, current)
+	if err != nil {
+		d.Log("[WARNING] failed to describe task definition for the audit record: %s", err)
+		return
+	}
+	opt := &DiffOption{Output: "json", w: io.Discard}
+	if _, err := diffTaskDefs(ctx, currentTd, prevTd, current, previous, opt); err != nil {
+		d.Log("[WARNING] failed to diff task definitions for the audit record: %s", err)
+		return
+	}
+	r.addDiffSummary(opt.changes)
+}
+
+type auditRecordKey struct{}
+
+func withAuditRecord(ctx context.Context, r *AuditRecord) context.Context {
+	return context.WithValue(ctx, auditRecordKey{}, r)
+}
+
+func auditRecordFromContext(ctx context.Context) *AuditRecord {
+	if r, ok := ctx.Value(auditRecordKey{}).(*AuditRecord); ok {
+		return r
+	}
+	return nil
+}
+
+// auditSink is a destination of audit records.
+type auditSink interface {
+	write(ctx context.Context, r *AuditRecord) error
+	// read returns the latest records of the service in chronological order.
+	read(ctx context.Context, cluster, service string, limit int) ([]*AuditRecord, error)
+}
+
+func (d *App) auditSinks() []auditSink {
+	c := d.config.Audit
+	var sinks []auditSink
+	if c.File != nil {
+		sinks = append(sinks, &fileAuditSink{path: c.File.Path})
+	}
+	if c.S3 != nil {
+		sinks = append(sinks, &s3AuditSink{client: s3.NewFromConfig(d.config.awsv2Config), bucket: c.S3.Bucket, prefix: c.S3.KeyPrefix})
+	}
+	if c.CloudWatchLogs != nil {
+		stream := c.CloudWatchLogs.LogStream
+		if stream == "" {
+			stream = d.Cluster + "/" + d.Service
+		}
+		sinks = append(sinks, &cwlAuditSink{client: d.cwl, group: c.CloudWatchLogs.LogGroup, stream: stream})
+	}
+	return sinks
+}
+
+// audit runs fn and writes an audit record of the command to the sinks.
+func (d *App) audit(ctx context.Context, command string, dryRun bool, fn func(context.Context) error) error {
+	if d.config.Audit == nil || dryRun {
+		return fn(ctx)
+	}
+	r := &AuditRecord{
+		Time:      time.Now(),
+		Command:   command,
+		Cluster:   d.Cluster,
+		Service:   d.Service,
+		Caller:    d.callerIdentity(ctx),
+		GitCommit: gitCommit(d.config.dir),
+	}
+	err := fn(withAuditRecord(ctx, r))
+	r.Duration = time.Since(r.Time).Round(time.Millisecond).String()
+	if err != nil {
+		r.Outcome = auditOutcomeFailure
+		r.Error = err.Error()
+	} else {
+		r.Outcome = auditOutcomeSuccess
+	}
+	// write the record even if ctx is canceled
+	for _, sink := range d.auditSinks() {
+		if werr := sink.write(context.Background(), r); werr != nil {
+			d.Log("[WARNING] failed to write the audit record: %s", werr)
+		}
+	}
+	return err
+}
+
+func (d *App) callerIdentity(ctx context.Context) string {
+	out, err := sts.NewFromConfig(d.config.awsv2Config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		d.Log("[WARNING] failed to get caller identity: %s", err)
+		return "unknown"
+	}
+	return aws.ToString(out.Arn)
+}
+
+var gitCommitEnvs = []string{
+	"GITHUB_SHA",
+	"CIRCLE_SHA1",
+	"CI_COMMIT_SHA",
+	"CODEBUILD_RESOLVED_SOURCE_VERSION",
+}
+
+// gitCommit returns the git commit of the dir from CI environment variables or git command.
+func gitCommit(dir string) string {
+	for _, name := range gitCommitEnvs {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+type HistoryOption struct {
+	Limit  int    `help:"number of records to show" default:"20"`
+	Output string `help:"output format (json, table, tsv)" default:"table" enum:"json,table,tsv"`
+}
+
+type auditRecords []*AuditRecord
+
+func (rs auditRecords) Header() []string {
+	return []string{"Time", "Command", "Caller", "Task Definition", "Outcome", "Git Commit"}
+}
+
+func (rs auditRecords) cols(r *AuditRecord) []string {
+	return []string{
+		r.Time.Local().Format(time.RFC3339),
+		r.Command,
+		r.Caller,
+		arnToName(r.TaskDefinition),
+		r.Outcome,
+		r.GitCommit,
+	}
+}
+
+func (rs auditRecords) OutputJSON(w io.Writer) error {
+	for _, r := range rs {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rs auditRecords) OutputTSV(w io.Writer) error {
+	for _, r := range rs {
+		if _, err := fmt.Fprintln(w, strings.Join(rs.cols(r), "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rs auditRecords) OutputTable(w io.Writer) error {
+	t := tablewriter.NewWriter(w)
+	t.SetHeader(rs.Header())
+	t.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	for _, r := range rs {
+		t.Append(rs.cols(r))
+	}
+	t.Render()
+	return nil
+}
+
+func (d *App) History(ctx context.Context, opt HistoryOption) error {
+	ctx, cancel := d.Start(ctx)
+	defer cancel()
+
+	if d.config.Audit == nil {
+		return errors.New("audit is not configured")
+	}
+	// read from the first sink
+	sink := d.auditSinks()[0]
+	records, err := sink.read(ctx, d.Cluster, d.Service, opt.Limit)
+	if err != nil {
+		return fmt.Errorf("failed to read audit records: %w", err)
+	}
+	rs := auditRecords(records)
+	switch opt.Output {
+	case "json":
+		return rs.OutputJSON(os.Stdout)
+	case "tsv":
+		return rs.OutputTSV(os.Stdout)
+	default:
+		return rs.OutputTable(os.Stdout)
+	}
+}
+
+// latestAuditRecords returns the last limit records of the service.
+func latestAuditRecords(records []*AuditRecord, cluster, service string, limit int) []*AuditRecord {
+	var rs []*AuditRecord
+	for _, r := range records {
+		if r.Cluster == cluster && r.Service == service {
+			rs = append(rs, r)
+		}
+	}
+	sort.SliceStable(rs, func(i, j int) bool {
+		return rs[i].Time.Before(rs[j].Time)
+	})
+	if limit > 0 && len(rs) > limit {
+		rs = rs[len(rs)-limit:]
+	}
+	return rs
+}
+
+// fileAuditSink appends records to a local JSONL file.
+type fileAuditSink struct {
+	path string
+}
+
+func (s *fileAuditSink) write(_ context.Context, r *AuditRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+func (s *fileAuditSink) read(_ context.Context, cluster, service string, limit int) ([]*AuditRecord, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var records []*AuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var r AuditRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", s.path, err)
+		}
+		records = append(records, &r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return latestAuditRecords(records, cluster, service, limit), nil
+}
+
+// s3AuditSink puts each record as an object under {key_prefix}{cluster}/{service}/.
+type s3AuditSink struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (s *s3AuditSink) servicePrefix(cluster, service string) string {
+	return s.prefix + cluster + "/" + service + "/"
+}
+
+func (s *s3AuditSink) write(ctx context.Context, r *AuditRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	// object keys are sorted in chronological order
+	key := s.servicePrefix(r.Cluster, r.Service) + r.Time.UTC().Format("20060102T150405.000000000Z") + "-" + r.Command + ".json"
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(b),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (s *s3AuditSink) read(ctx context.Context, cluster, service string, limit int) ([]*AuditRecord, error) {
+	var keys []string
+	p := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.servicePrefix(cluster, service)),
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range out.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[len(keys)-limit:]
+	}
+	records := make([]*AuditRecord, 0, len(keys))
+	for _, key := range keys {
+		out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		var r AuditRecord
+		err = json.NewDecoder(out.Body).Decode(&r)
+		out.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse s3://%s/%s: %w", s.bucket, key, err)
+		}
+		records = append(records, &r)
+	}
+	return records, nil
+}
+
+// cwlAuditSink puts records to a CloudWatch Logs stream.
+type cwlAuditSink struct {
+	client *cloudwatchlogs.Client
+	group  string
+	stream string
+}
+
+func (s *cwlAuditSink) write(ctx context.Context, r *AuditRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	in := &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(s.group),
+		LogStreamName: aws.String(s.stream),
+		LogEvents: []cwlTypes.InputLogEvent{
+			{Message: aws.String(string(b)), Timestamp: aws.Int64(r.Time.UnixMilli())},
+		},
+	}
+	_, err = s.client.PutLogEvents(ctx, in)
+	var nf *cwlTypes.ResourceNotFoundException
+	if errors.As(err, &nf) {
+		// create the log stream and retry
+		if _, err := s.client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+			LogGroupName:  aws.String(s.group),
+			LogStreamName: aws.String(s.stream),
+		}); err != nil {
+			return fmt.Errorf("failed to create log stream %s: %w", s.stream, err)
+		}
+		_, err = s.client.PutLogEvents(ctx, in)
+	}
+	return err
+}
+
+func (s *cwlAuditSink) read(ctx context.Context, cluster, service string, limit int) ([]*AuditRecord, error) {
+	// the stream may have records of other services, so read backward until limit records of the service are found.
+	var records []*AuditRecord
+	var found int
+	var token *string
+	for {
+		out, err := s.client.GetLogEvents(ctx, &cloudwatchlogs.GetLogEventsInput{
+			LogGroupName:  aws.String(s.group),
+			LogStreamName: aws.String(s.stream),
+			StartFromHead: aws.Bool(false),
+			NextToken:     token,
+		})
+		if err != nil {
+			var nf *cwlTypes.ResourceNotFoundException
+			if errors.As(err, &nf) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		var page []*AuditRecord
+		for _, e := range out.Events {
+			var r AuditRecord
+			if err := json.Unmarshal([]byte(aws.ToString(e.Message)), &r); err != nil {
+				continue // not an audit record
+			}
+			if r.Cluster == cluster && r.Service == service {
+				found++
+			}
+			page = append(page, &r)
+		}
+		// pages are read backward
+		records = append(page, records...)
+		if limit > 0 && found >= limit {
+			break
+		}
+		// the same token is returned at the beginning of the stream
+		if len(out.Events) == 0 || out.NextBackwardToken == nil || aws.ToString(out.NextBackwardToken) == aws.ToString(token) {
+			break
+		}
+		token = out.NextBackwardToken
+	}
+	return latestAuditRecords(records, cluster, service, limit), nil
+}
@@ -0,0 +1,159 @@
+package ecspresso_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwlTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestFileAuditSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "ecspresso.jsonl")
+
+	// no records
+	rs, err := ecspresso.ReadAuditRecordsFromFile(path, "default", "web", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 0 {
+		t.Errorf("unexpected records: %v", rs)
+	}
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	records := []*ecspresso.AuditRecord{
+		{Time: now, Command: "deploy", Cluster: "default", Service: "web", Outcome: "success", TaskDefinition: "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/web:1"},
+		{Time: now.Add(time.Minute), Command: "deploy", Cluster: "default", Service: "api", Outcome: "success"},
+		{Time: now.Add(2 * time.Minute), Command: "scale", Cluster: "default", Service: "web", Outcome: "failure", Error: "failed"},
+		{Time: now.Add(3 * time.Minute), Command: "rollback", Cluster: "default", Service: "web", Outcome: "success"},
+	}
+	for _, r := range records {
+		if err := ecspresso.WriteAuditRecordToFile(path, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rs, err = ecspresso.ReadAuditRecordsFromFile(path, "default", "web", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*ecspresso.AuditRecord{records[0], records[2], records[3]}, rs); diff != "" {
+		t.Errorf("unexpected records: %s", diff)
+	}
+
+	rs, err = ecspresso.ReadAuditRecordsFromFile(path, "default", "web", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*ecspresso.AuditRecord{records[2], records[3]}, rs); diff != "" {
+		t.Errorf("unexpected latest records: %s", diff)
+	}
+}
+
+func TestAuditRecordDiffSummary(t *testing.T) {
+	r := &ecspresso.AuditRecord{}
+	r.AddDiffSummary([]ecspresso.DiffChange{
+		{Resource: "service", Path: "desiredCount", Type: "update", Old: 1, New: 2},
+		{Resource: "service", Path: "tags[Env]", Type: "add", New: "prod"},
+	})
+	expected := []string{"update service.desiredCount", "add service.tags[Env]"}
+	if diff := cmp.Diff(expected, r.DiffSummary); diff != "" {
+		t.Errorf("unexpected diff summary: %s", diff)
+	}
+}
+
+func TestCloudWatchLogsAuditSinkRead(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	record := func(i int, service string) *ecspresso.AuditRecord {
+		return &ecspresso.AuditRecord{Time: now.Add(time.Duration(i) * time.Minute), Command: "deploy", Cluster: "default", Service: service, Outcome: "success"}
+	}
+	event := func(r *ecspresso.AuditRecord) cwlTypes.OutputLogEvent {
+		b, _ := json.Marshal(r)
+		return cwlTypes.OutputLogEvent{Message: aws.String(string(b)), Timestamp: aws.Int64(r.Time.UnixMilli())}
+	}
+	// the shared stream has records of the other service in the latest page
+	older := []*ecspresso.AuditRecord{record(0, "test"), record(1, "other"), record(2, "test")}
+	latest := []*ecspresso.AuditRecord{record(3, "other"), record(4, "test"), record(5, "other")}
+	pages := map[string]*cloudwatchlogs.GetLogEventsOutput{
+		"": {
+			Events:            []cwlTypes.OutputLogEvent{event(latest[0]), event(latest[1]), event(latest[2])},
+			NextBackwardToken: aws.String("b/1"),
+		},
+		"b/1": {
+			Events:            []cwlTypes.OutputLogEvent{event(older[0]), event(older[1]), event(older[2])},
+			NextBackwardToken: aws.String("b/2"),
+		},
+		"b/2": {
+			NextBackwardToken: aws.String("b/2"),
+		},
+	}
+	app, m := newSDKMockApp(t, "tests/alarm/ecspresso.yml", map[string]any{
+		"GetLogEvents": func(in any) (any, error) {
+			return pages[aws.ToString(in.(*cloudwatchlogs.GetLogEventsInput).NextToken)], nil
+		},
+	})
+	ctx := context.Background()
+
+	rs, err := app.ReadAuditRecordsFromCloudWatchLogs(ctx, "/ecspresso/audit", "shared", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*ecspresso.AuditRecord{older[2], latest[1]}, rs); diff != "" {
+		t.Errorf("unexpected latest records: %s", diff)
+	}
+	if n := m.count("GetLogEvents"); n != 2 {
+		t.Errorf("expected to read 2 pages, got %d", n)
+	}
+
+	rs, err = app.ReadAuditRecordsFromCloudWatchLogs(ctx, "/ecspresso/audit", "shared", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]*ecspresso.AuditRecord{older[0], older[2], latest[1]}, rs); diff != "" {
+		t.Errorf("unexpected records: %s", diff)
+	}
+}
+
+func TestAuditRecordTaskDefinitionDiffSummary(t *testing.T) {
+	tds := map[string]*types.TaskDefinition{
+		"web:1": {
+			Family: aws.String("web"),
+			ContainerDefinitions: []types.ContainerDefinition{
+				{Name: aws.String("app"), Image: aws.String("nginx:1.25")},
+			},
+		},
+		"web:2": {
+			Family: aws.String("web"),
+			ContainerDefinitions: []types.ContainerDefinition{
+				{Name: aws.String("app"), Image: aws.String("nginx:1.27")},
+			},
+		},
+	}
+	app, _ := newSDKMockApp(t, "tests/alarm/ecspresso.yml", map[string]any{
+		"DescribeTaskDefinition": func(in any) (any, error) {
+			return &ecs.DescribeTaskDefinitionOutput{
+				TaskDefinition: tds[aws.ToString(in.(*ecs.DescribeTaskDefinitionInput).TaskDefinition)],
+			}, nil
+		},
+	})
+	ctx := context.Background()
+
+	r := &ecspresso.AuditRecord{}
+	app.AddTaskDefinitionDiffSummary(ctx, r, "web:1", "web:1")
+	if len(r.DiffSummary) != 0 {
+		t.Errorf("unexpected diff summary for the same task definition: %v", r.DiffSummary)
+	}
+	app.AddTaskDefinitionDiffSummary(ctx, r, "web:1", "web:2")
+	expected := []string{"update taskdef.containerDefinitions[name=app].image"}
+	if diff := cmp.Diff(expected, r.DiffSummary); diff != "" {
+		t.Errorf("unexpected diff summary: %s", diff)
+	}
+}
@@ -0,0 +1,162 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+// isBlueGreen returns true when the service is deployed by the ECS native blue/green deployment strategy.
+func (sv *Service) isBlueGreen() bool {
+	if sv.DeploymentController != nil && sv.DeploymentController.Type != types.DeploymentControllerTypeEcs {
+		return false
+	}
+	return sv.DeploymentConfiguration != nil && sv.DeploymentConfiguration.Strategy == types.DeploymentStrategyBlueGreen
+}
+
+// findInProgressServiceDeployment returns the service deployment in progress.
+func (d *App) findInProgressServiceDeployment(ctx context.Context) (*types.ServiceDeployment, error) {
+	out, err := d.ecs.ListServiceDeployments(ctx, &ecs.ListServiceDeploymentsInput{
+		Cluster: aws.String(d.Cluster),
+		Service: aws.String(d.Service),
+		Status: []types.ServiceDeploymentStatus{
+			types.ServiceDeploymentStatusPending,
+			types.ServiceDeploymentStatusInProgress,
+			types.ServiceDeploymentStatusRollbackRequested,
+			types.ServiceDeploymentStatusRollbackInProgress,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service deployments: %w", err)
+	}
+	if len(out.ServiceDeployments) == 0 {
+		return nil, ErrNotFound("no service deployments in progress")
+	}
+	dp, err := d.ecs.DescribeServiceDeployments(ctx, &ecs.DescribeServiceDeploymentsInput{
+		ServiceDeploymentArns: []string{aws.ToString(out.ServiceDeployments[0].ServiceDeploymentArn)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service deployments: %w", err)
+	}
+	if len(dp.ServiceDeployments) == 0 {
+		return nil, ErrNotFound(fmt.Sprintf("service deployment %s is not found", aws.ToString(out.ServiceDeployments[0].ServiceDeploymentArn)))
+	}
+	return &dp.ServiceDeployments[0], nil
+}
+
+type lifecycleState struct {
+	arn    string
+	status types.ServiceDeploymentStatus
+	stage  types.ServiceDeploymentLifecycleStage
+}
+
+// showLifecycleStage shows the lifecycle stage of the service deployment in progress when it is changed.
+func (d *App) showLifecycleStage(ctx context.Context, st *lifecycleState) error {
+	dp, err := d.findInProgressServiceDeployment(ctx)
+	if err != nil {
+		if errors.As(err, &errNotFound) {
+			return nil
+		}
+		return err
+	}
+	arn := aws.ToString(dp.ServiceDeploymentArn)
+	if st.arn == arn && st.status == dp.Status && st.stage == dp.LifecycleStage {
+		return nil
+	}
+	st.arn, st.status, st.stage = arn, dp.Status, dp.LifecycleStage
+	d.Log("Service deployment %s %s lifecycle stage: %s", arnToName(arn), dp.Status, dp.LifecycleStage)
+	return nil
+}
+
+// WaitServiceBlueGreen waits for the service stable with showing the lifecycle stages of the blue/green deployment.
+func (d *App) WaitServiceBlueGreen(ctx context.Context, sv *Service) error {
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		tick := time.NewTicker(10 * time.Second)
+		defer tick.Stop()
+		st := &lifecycleState{}
+		for {
+			if err := d.showLifecycleStage(waitCtx, st); err != nil && waitCtx.Err() == nil {
+				d.Log("[WARNING] %s", err)
+			}
+			select {
+			case <-waitCtx.Done():
+				return
+			case <-tick.C:
+			}
+		}
+	}()
+	return d.WaitServiceStable(ctx, sv)
+}
+
+// RollbackBlueGreen rolls back the blue/green deployment in progress.
+// When no deployments are in progress, the service is rolled back by updating the task definition.
+func (d *App) RollbackBlueGreen(ctx context.Context, sv *Service, targetArn string, opt RollbackOption) (string, error) {
+	dp, err := d.findInProgressServiceDeployment(ctx)
+	if err != nil {
+		if errors.As(err, &errNotFound) {
+			d.Log("[DEBUG] %s", err)
+			return d.RollbackServiceTasks(ctx, sv, targetArn, opt)
+		}
+		return "", err
+	}
+	arn := aws.ToString(dp.ServiceDeploymentArn)
+	d.Log("the service deployment in progress found, rolling back %s %s", arnToName(arn), opt.DryRunString())
+	if opt.DryRun {
+		return *sv.TaskDefinition, nil
+	}
+	if _, err := d.ecs.StopServiceDeployment(ctx, &ecs.StopServiceDeploymentInput{
+		ServiceDeploymentArn: dp.ServiceDeploymentArn,
+		StopType:             types.StopServiceDeploymentStopTypeRollback,
+	}); err != nil {
+		return "", fmt.Errorf("failed to roll back the service deployment: %w", err)
+	}
+	return *sv.TaskDefinition, nil
+}
+
+// blueGreenRollbackTarget returns the task definition of the source service revision of the deployment in progress.
+// ECS rolls back the deployment to the source revision, which may not be the previous revision of the task definition.
+func (d *App) blueGreenRollbackTarget(ctx context.Context) (string, error) {
+	dp, err := d.findInProgressServiceDeployment(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(dp.SourceServiceRevisions) == 0 {
+		return "", ErrNotFound(fmt.Sprintf("source service revision of %s is not found", arnToName(aws.ToString(dp.ServiceDeploymentArn))))
+	}
+	revArn := aws.ToString(dp.SourceServiceRevisions[0].Arn)
+	out, err := d.ecs.DescribeServiceRevisions(ctx, &ecs.DescribeServiceRevisionsInput{
+		ServiceRevisionArns: []string{revArn},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe service revisions: %w", err)
+	}
+	if len(out.ServiceRevisions) == 0 {
+		return "", ErrNotFound(fmt.Sprintf("service revision %s is not found", revArn))
+	}
+	return aws.ToString(out.ServiceRevisions[0].TaskDefinition), nil
+}
+
+func (d *App) verifyLifecycleHook(ctx context.Context, hook types.DeploymentLifecycleHook) error {
+	if len(hook.LifecycleStages) == 0 {
+		return errors.New("lifecycleStages is required")
+	}
+	if err := d.verifyLambdaFunction(ctx, aws.ToString(hook.HookTargetArn)); err != nil {
+		return err
+	}
+	roleName, err := extractRoleName(aws.ToString(hook.RoleArn))
+	if err != nil {
+		return err
+	}
+	if _, err := d.iam.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)}); err != nil {
+		return fmt.Errorf("failed to get role %s: %w", roleName, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package ecspresso_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+const (
+	testServiceDeploymentArn = "arn:aws:ecs:ap-northeast-1:123456789012:service-deployment/default/test/abcdefg"
+	testSourceRevisionArn    = "arn:aws:ecs:ap-northeast-1:123456789012:service-revision/default/test/1234567890"
+)
+
+// newBlueGreenTestApp returns an App which has a service deployment in progress when inProgress is true.
+func newBlueGreenTestApp(t *testing.T, inProgress bool) (*ecspresso.App, *sdkMock) {
+	list := &ecs.ListServiceDeploymentsOutput{}
+	if inProgress {
+		list.ServiceDeployments = []types.ServiceDeploymentBrief{
+			{ServiceDeploymentArn: ptr(testServiceDeploymentArn), Status: types.ServiceDeploymentStatusInProgress},
+		}
+	}
+	return newSDKMockApp(t, "tests/alarm/ecspresso.yml", map[string]any{
+		"ListServiceDeployments": list,
+		"DescribeServiceDeployments": &ecs.DescribeServiceDeploymentsOutput{
+			ServiceDeployments: []types.ServiceDeployment{
+				{
+					ServiceDeploymentArn: ptr(testServiceDeploymentArn),
+					Status:               types.ServiceDeploymentStatusInProgress,
+					LifecycleStage:       types.ServiceDeploymentLifecycleStageBakeTime,
+					SourceServiceRevisions: []types.ServiceRevisionSummary{
+						{Arn: ptr(testSourceRevisionArn)},
+					},
+				},
+			},
+		},
+		"DescribeServiceRevisions": &ecs.DescribeServiceRevisionsOutput{
+			ServiceRevisions: []types.ServiceRevision{
+				{
+					ServiceRevisionArn: ptr(testSourceRevisionArn),
+					TaskDefinition:     ptr("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:5"),
+				},
+			},
+		},
+		"StopServiceDeployment": &ecs.StopServiceDeploymentOutput{ServiceDeploymentArn: ptr(testServiceDeploymentArn)},
+	})
+}
+
+func blueGreenService() *ecspresso.Service {
+	return &ecspresso.Service{
+		Service: types.Service{
+			TaskDefinition: ptr("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:2"),
+			DeploymentConfiguration: &types.DeploymentConfiguration{
+				Strategy: types.DeploymentStrategyBlueGreen,
+			},
+		},
+	}
+}
+
+func TestIsBlueGreen(t *testing.T) {
+	if sv := blueGreenService(); !sv.IsBlueGreen() {
+		t.Error("BLUE_GREEN strategy service should be blue/green")
+	}
+	sv := blueGreenService()
+	sv.DeploymentController = &types.DeploymentController{Type: types.DeploymentControllerTypeCodeDeploy}
+	if sv.IsBlueGreen() {
+		t.Error("CODE_DEPLOY controller service should not be blue/green")
+	}
+	sv = blueGreenService()
+	sv.DeploymentConfiguration.Strategy = types.DeploymentStrategyRolling
+	if sv.IsBlueGreen() {
+		t.Error("ROLLING strategy service should not be blue/green")
+	}
+}
+
+func TestRollbackBlueGreen(t *testing.T) {
+	ctx := context.Background()
+	target := "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:1"
+	t.Run("in progress", func(t *testing.T) {
+		app, m := newBlueGreenTestApp(t, true)
+		tdArn, err := app.RollbackBlueGreen(ctx, blueGreenService(), target, ecspresso.RollbackOption{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tdArn != "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:2" {
+			t.Errorf("unexpected rolled-back task definition %s", tdArn)
+		}
+		v, ok := m.called("StopServiceDeployment")
+		if !ok {
+			t.Fatal("StopServiceDeployment is not called")
+		}
+		in := v.(*ecs.StopServiceDeploymentInput)
+		if in.StopType != types.StopServiceDeploymentStopTypeRollback || *in.ServiceDeploymentArn != testServiceDeploymentArn {
+			t.Errorf("unexpected input %#v", in)
+		}
+	})
+	t.Run("in progress dry run", func(t *testing.T) {
+		app, m := newBlueGreenTestApp(t, true)
+		if _, err := app.RollbackBlueGreen(ctx, blueGreenService(), target, ecspresso.RollbackOption{DryRun: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m.called("StopServiceDeployment"); ok {
+			t.Error("StopServiceDeployment is called in dry run")
+		}
+	})
+	t.Run("not in progress", func(t *testing.T) {
+		app, m := newBlueGreenTestApp(t, false)
+		// falls back to update the service tasks
+		if _, err := app.RollbackBlueGreen(ctx, blueGreenService(), target, ecspresso.RollbackOption{DryRun: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m.called("StopServiceDeployment"); ok {
+			t.Error("StopServiceDeployment is called without deployments in progress")
+		}
+	})
+}
+
+func TestBlueGreenRollbackTarget(t *testing.T) {
+	app, _ := newBlueGreenTestApp(t, true)
+	// the source revision of the deployment in progress, not the previous revision of the task definition
+	target, err := app.RollbackTarget(context.Background(), blueGreenService())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:5" {
+		t.Errorf("unexpected rollback target %s", target)
+	}
+}
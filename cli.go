@@ -17,23 +17,29 @@ type CLIOptions struct {
 	Timeout        *time.Duration    `help:"timeout. Override in a configuration file." env:"ECSPRESSO_TIMEOUT"`
 	FilterCommand  string            `help:"filter command" env:"ECSPRESSO_FILTER_COMMAND"`
 	Color          bool              `help:"enable colorized output" env:"ECSPRESSO_COLOR" default:"true" negatable:""`
+	LockTimeout    *time.Duration    `help:"timeout to wait for the deployment lock. Override in a configuration file." env:"ECSPRESSO_LOCK_TIMEOUT"`
 
 	Appspec    *AppSpecOption    `cmd:"" help:"output AppSpec YAML for CodeDeploy to STDOUT"`
+	CodeDeploy *CodeDeployOption `cmd:"" name:"codedeploy" help:"control the in-progress deployment of CodeDeploy (continue, stop, skip-wait)"`
 	Delete     *DeleteOption     `cmd:"" help:"delete service"`
 	Deploy     *DeployOption     `cmd:"" help:"deploy service"`
 	Deregister *DeregisterOption `cmd:"" help:"deregister task definition"`
 	Diff       *DiffOption       `cmd:"" help:"show diff between task definition, service definition with current running service and task definition"`
 	Exec       *ExecOption       `cmd:"" help:"execute command on task"`
+	History    *HistoryOption    `cmd:"" help:"show audit records of mutating commands for the service"`
 	Init       *InitOption       `cmd:"" help:"create configuration files from existing ECS service"`
 	Refresh    *RefreshOption    `cmd:"" help:"refresh service. equivalent to deploy --skip-task-definition --force-new-deployment --no-update-service"`
 	Register   *RegisterOption   `cmd:"" help:"register task definition"`
 	Render     *RenderOption     `cmd:"" help:"render config, service definition or task definition file to STDOUT"`
+	Restore    *RestoreOption    `cmd:"" help:"restore service from a snapshot"`
 	Revisions  *RevisionsOption  `cmd:"" help:"show revisions of task definitions"`
 	Rollback   *RollbackOption   `cmd:"" help:"rollback service"`
 	Run        *RunOption        `cmd:"" help:"run task"`
 	Scale      *ScaleOption      `cmd:"" help:"scale service. equivalent to deploy --skip-task-definition --no-update-service"`
+	Snapshot   *SnapshotOption   `cmd:"" help:"save service definition, task definition and auto scaling settings of the service to a directory"`
 	Status     *StatusOption     `cmd:"" help:"show status of service"`
 	Tasks      *TasksOption      `cmd:"" help:"list tasks that are in a service or having the same family"`
+	Unlock     *UnlockOption     `cmd:"" help:"release the deployment lock of the service"`
 	Verify     *VerifyOption     `cmd:"" help:"verify resources in configurations"`
 	Wait       *WaitOption       `cmd:"" help:"wait until service stable"`
 	Version    struct{}          `cmd:"" help:"show version"`
@@ -61,6 +67,8 @@ func (opts *CLIOptions) ForSubCommand(sub string) interface{} {
 	switch sub {
 	case "appspec":
 		return opts.Appspec
+	case "codedeploy":
+		return opts.CodeDeploy
 	case "delete":
 		return opts.Delete
 	case "deploy":
@@ -71,6 +79,8 @@ func (opts *CLIOptions) ForSubCommand(sub string) interface{} {
 		return opts.Diff
 	case "exec":
 		return opts.Exec
+	case "history":
+		return opts.History
 	case "init":
 		return opts.Init
 	case "refresh":
@@ -79,6 +89,8 @@ func (opts *CLIOptions) ForSubCommand(sub string) interface{} {
 		return opts.Register
 	case "render":
 		return opts.Render
+	case "restore":
+		return opts.Restore
 	case "revisions":
 		return opts.Revisions
 	case "rollback":
@@ -87,10 +99,14 @@ func (opts *CLIOptions) ForSubCommand(sub string) interface{} {
 		return opts.Run
 	case "scale":
 		return opts.Scale
+	case "snapshot":
+		return opts.Snapshot
 	case "status":
 		return opts.Status
 	case "tasks":
 		return opts.Tasks
+	case "unlock":
+		return opts.Unlock
 	case "verify":
 		return opts.Verify
 	case "wait":
@@ -114,6 +130,10 @@ func dispatchCLI(ctx context.Context, sub string, usage func(), opts *CLIOptions
 		}
 		appOpts = append(appOpts, WithConfig(config))
 	}
+	if sub == "restore" {
+		// load the config in the snapshot
+		opts.ConfigFilePath = opts.Restore.configFilePath()
+	}
 	app, err := New(ctx, opts, appOpts...)
 	if err != nil {
 		return err
@@ -121,19 +141,29 @@ func dispatchCLI(ctx context.Context, sub string, usage func(), opts *CLIOptions
 	app.Log("[DEBUG] dispatching subcommand: %s", sub)
 	switch sub {
 	case "deploy":
-		return app.Deploy(ctx, *opts.Deploy)
+		return app.audit(ctx, sub, opts.Deploy.DryRun, func(ctx context.Context) error {
+			return app.Deploy(ctx, *opts.Deploy)
+		})
 	case "refresh":
-		return app.Deploy(ctx, opts.Refresh.DeployOption())
+		return app.audit(ctx, sub, opts.Refresh.DryRun, func(ctx context.Context) error {
+			return app.Deploy(ctx, opts.Refresh.DeployOption())
+		})
 	case "scale":
-		return app.Deploy(ctx, opts.Scale.DeployOption())
+		return app.audit(ctx, sub, opts.Scale.DryRun, func(ctx context.Context) error {
+			return app.Deploy(ctx, opts.Scale.DeployOption())
+		})
 	case "status":
 		return app.Status(ctx, *opts.Status)
 	case "rollback":
-		return app.Rollback(ctx, *opts.Rollback)
+		return app.audit(ctx, sub, opts.Rollback.DryRun, func(ctx context.Context) error {
+			return app.Rollback(ctx, *opts.Rollback)
+		})
 	case "create":
 		return fmt.Errorf("create command is deprecated. use deploy command instead")
 	case "delete":
-		return app.Delete(ctx, *opts.Delete)
+		return app.audit(ctx, sub, opts.Delete.DryRun, func(ctx context.Context) error {
+			return app.Delete(ctx, *opts.Delete)
+		})
 	case "run":
 		return app.Run(ctx, *opts.Run)
 	case "wait":
@@ -144,9 +174,24 @@ func dispatchCLI(ctx context.Context, sub string, usage func(), opts *CLIOptions
 		return app.Deregister(ctx, *opts.Deregister)
 	case "revisions":
 		return app.Revisions(ctx, *opts.Revisions)
+	case "snapshot":
+		return app.Snapshot(ctx, *opts.Snapshot)
+	case "restore":
+		return app.audit(ctx, sub, opts.Restore.DryRun, func(ctx context.Context) error {
+			return app.Restore(ctx, *opts.Restore)
+		})
 	case "init":
 		return app.Init(ctx, *opts.Init)
 	case "diff":
+		if path := opts.Diff.CompareConfig; path != "" {
+			otherOpts := *opts
+			otherOpts.ConfigFilePath = path
+			other, err := New(ctx, &otherOpts)
+			if err != nil {
+				return err
+			}
+			return app.DiffConfig(ctx, other, *opts.Diff)
+		}
 		return app.Diff(ctx, *opts.Diff)
 	case "appspec":
 		return app.AppSpec(ctx, *opts.Appspec)
@@ -158,6 +203,14 @@ func dispatchCLI(ctx context.Context, sub string, usage func(), opts *CLIOptions
 		return app.Tasks(ctx, *opts.Tasks)
 	case "exec":
 		return app.Exec(ctx, *opts.Exec)
+	case "history":
+		return app.History(ctx, *opts.History)
+	case "unlock":
+		return app.Unlock(ctx, *opts.Unlock)
+	case "codedeploy":
+		return app.audit(ctx, sub+" "+opts.CodeDeploy.command, false, func(ctx context.Context) error {
+			return app.CodeDeploy(ctx, *opts.CodeDeploy)
+		})
 	default:
 		usage()
 	}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/google/go-cmp/cmp"
@@ -116,6 +117,7 @@ var cliTests = []struct {
 			RollbackEvents:       "",
 			UpdateService:        true,
 			LatestTaskDefinition: false,
+			SmokeTest:            true,
 		},
 	},
 	{
@@ -133,6 +135,58 @@ var cliTests = []struct {
 			RollbackEvents:       "",
 			UpdateService:        true,
 			LatestTaskDefinition: true,
+			SmokeTest:            true,
+		},
+	},
+	{
+		args: []string{"deploy", "--pre-run=migrate.jsonnet", "--pre-run-watch-container=app", "--no-smoke-test"},
+		sub:  "deploy",
+		subOption: &ecspresso.DeployOption{
+			DesiredCount:         ptr(int32(-1)),
+			Wait:                 true,
+			UpdateService:        true,
+			PreRun:               "migrate.jsonnet",
+			PreRunWatchContainer: "app",
+			SmokeTest:            false,
+		},
+	},
+	{
+		args: []string{"codedeploy", "continue", "--no-wait"},
+		sub:  "codedeploy",
+		fn: func(t *testing.T, o any) {
+			opt := o.(*ecspresso.CodeDeployOption)
+			if opt.Command() != "continue" || opt.Continue.Wait {
+				t.Errorf("unexpected option: %s %#v", opt.Command(), opt.Continue)
+			}
+		},
+	},
+	{
+		args: []string{"codedeploy", "abort", "--no-rollback"},
+		sub:  "codedeploy",
+		fn: func(t *testing.T, o any) {
+			opt := o.(*ecspresso.CodeDeployOption)
+			if opt.Command() != "stop" || opt.Stop.Rollback {
+				t.Errorf("unexpected option: %s %#v", opt.Command(), opt.Stop)
+			}
+		},
+	},
+	{
+		args: []string{"codedeploy", "stop"},
+		sub:  "codedeploy",
+		fn: func(t *testing.T, o any) {
+			opt := o.(*ecspresso.CodeDeployOption)
+			if opt.Command() != "stop" || !opt.Stop.Rollback {
+				t.Errorf("unexpected option: %s %#v", opt.Command(), opt.Stop)
+			}
+		},
+	},
+	{
+		args: []string{"codedeploy", "skip-wait"},
+		sub:  "codedeploy",
+		fn: func(t *testing.T, o any) {
+			if opt := o.(*ecspresso.CodeDeployOption); opt.Command() != "skip-wait" {
+				t.Errorf("unexpected command: %s", opt.Command())
+			}
 		},
 	},
 	{
@@ -150,6 +204,7 @@ var cliTests = []struct {
 			RollbackEvents:       "",
 			UpdateService:        true,
 			LatestTaskDefinition: false,
+			SmokeTest:            true,
 		},
 	},
 	{
@@ -167,6 +222,7 @@ var cliTests = []struct {
 			RollbackEvents:       "",
 			UpdateService:        true,
 			LatestTaskDefinition: false,
+			SmokeTest:            true,
 		},
 	},
 	{
@@ -186,6 +242,7 @@ var cliTests = []struct {
 			UpdateService:        true,
 			LatestTaskDefinition: false,
 			Revision:             0,
+			SmokeTest:            true,
 		},
 	},
 	{
@@ -419,6 +476,87 @@ var cliTests = []struct {
 			Terminate: true,
 		},
 	},
+	{
+		args: []string{"delete", "--ignore-deletion-protection", "--scale-to-zero", "--deregister-task-definitions", "--snapshot", "backup"},
+		sub:  "delete",
+		subOption: &ecspresso.DeleteOption{
+			IgnoreDeletionProtection:  true,
+			ScaleToZero:               true,
+			DeregisterTaskDefinitions: true,
+			Snapshot:                  "backup",
+		},
+	},
+	{
+		args: []string{"snapshot"},
+		sub:  "snapshot",
+		subOption: &ecspresso.SnapshotOption{
+			Dir: "",
+		},
+	},
+	{
+		args: []string{"snapshot", "--dir", "backup"},
+		sub:  "snapshot",
+		subOption: &ecspresso.SnapshotOption{
+			Dir: "backup",
+		},
+	},
+	{
+		args: []string{"restore", "backup"},
+		sub:  "restore",
+		subOption: &ecspresso.RestoreOption{
+			Dir:         "backup",
+			AutoScaling: true,
+			Wait:        true,
+		},
+	},
+	{
+		args: []string{"restore", "backup", "--dry-run", "--cluster", "other", "--service", "clone", "--no-auto-scaling", "--no-wait"},
+		sub:  "restore",
+		subOption: &ecspresso.RestoreOption{
+			Dir:         "backup",
+			DryRun:      true,
+			Cluster:     "other",
+			Service:     "clone",
+			AutoScaling: false,
+			Wait:        false,
+		},
+	},
+	{
+		args: []string{"history"},
+		sub:  "history",
+		subOption: &ecspresso.HistoryOption{
+			Limit:  20,
+			Output: "table",
+		},
+	},
+	{
+		args: []string{"history", "--limit", "5", "--output", "json"},
+		sub:  "history",
+		subOption: &ecspresso.HistoryOption{
+			Limit:  5,
+			Output: "json",
+		},
+	},
+	{
+		args: []string{"unlock"},
+		sub:  "unlock",
+		subOption: &ecspresso.UnlockOption{
+			Force: false,
+		},
+	},
+	{
+		args: []string{"--lock-timeout", "1m", "unlock", "--force"},
+		sub:  "unlock",
+		option: &ecspresso.CLIOptions{
+			ConfigFilePath: "ecspresso.yml",
+			ExtStr:         map[string]string{},
+			ExtCode:        map[string]string{},
+			LockTimeout:    ptr(time.Minute),
+		},
+		subOption: &ecspresso.UnlockOption{
+			Force: true,
+		},
+	},
 	{
 		args: []string{"run"},
 		sub:  "run",
@@ -621,6 +759,7 @@ var cliTests = []struct {
 			ServiceDefinitionPath: "ecs-service-def.json",
 			ForceOverwrite:        false,
 			Jsonnet:               false,
+			RewriteMap:            "rewrite-map.json",
 		},
 	},
 	{
@@ -640,6 +779,7 @@ var cliTests = []struct {
 			ServiceDefinitionPath: "ecs-service-def.json",
 			ForceOverwrite:        false,
 			Jsonnet:               false,
+			RewriteMap:            "rewrite-map.json",
 		},
 	},
 	{
@@ -658,6 +798,33 @@ var cliTests = []struct {
 			ServiceDefinitionPath: "servicedef.jsonnet",
 			ForceOverwrite:        true,
 			Jsonnet:               true,
+			RewriteMap:            "rewrite-map.json",
+		},
+	},
+	{
+		args: []string{"init", "--service", "myservice", "--rewrite", "--rewrite-map", "ext-vars.json"},
+		sub:  "init",
+		subOption: &ecspresso.InitOption{
+			Region:                os.Getenv("AWS_REGION"),
+			Cluster:               "default",
+			Service:               "myservice",
+			TaskDefinitionPath:    "ecs-task-def.json",
+			ServiceDefinitionPath: "ecs-service-def.json",
+			Rewrite:               true,
+			RewriteMap:            "ext-vars.json",
+		},
+	},
+	{
+		args: []string{"init", "--service", "myservice", "--deployment-group-definition-path", "ecs-deployment-group.json"},
+		sub:  "init",
+		subOption: &ecspresso.InitOption{
+			Region:                        os.Getenv("AWS_REGION"),
+			Cluster:                       "default",
+			Service:                       "myservice",
+			TaskDefinitionPath:            "ecs-task-def.json",
+			ServiceDefinitionPath:         "ecs-service-def.json",
+			DeploymentGroupDefinitionPath: "ecs-deployment-group.json",
+			RewriteMap:                    "rewrite-map.json",
 		},
 	},
 	{
@@ -672,6 +839,7 @@ var cliTests = []struct {
 			ServiceDefinitionPath: "ecs-service-def.json",
 			ForceOverwrite:        false,
 			Jsonnet:               false,
+			RewriteMap:            "rewrite-map.json",
 		},
 	},
 	{
@@ -679,6 +847,8 @@ var cliTests = []struct {
 		sub:  "diff",
 		subOption: &ecspresso.DiffOption{
 			Unified: true,
+			Output:  "text",
+			Format:  "text",
 		},
 	},
 	{
@@ -686,6 +856,48 @@ var cliTests = []struct {
 		sub:  "diff",
 		subOption: &ecspresso.DiffOption{
 			Unified: false,
+			Output:  "text",
+			Format:  "text",
+		},
+	},
+	{
+		args: []string{"diff", "--output", "json"},
+		sub:  "diff",
+		subOption: &ecspresso.DiffOption{
+			Unified: true,
+			Output:  "json",
+			Format:  "text",
+		},
+	},
+	{
+		args: []string{"diff", "--revision", "41", "--to-revision", "42"},
+		sub:  "diff",
+		subOption: &ecspresso.DiffOption{
+			Unified:    true,
+			Output:     "text",
+			Format:     "text",
+			Revision:   41,
+			ToRevision: 42,
+		},
+	},
+	{
+		args: []string{"diff", "--compare-config", "prod.yml"},
+		sub:  "diff",
+		subOption: &ecspresso.DiffOption{
+			Unified:       true,
+			Output:        "text",
+			Format:        "text",
+			CompareConfig: "prod.yml",
+		},
+	},
+	{
+		args: []string{"diff", "--format", "markdown", "--output-file", "diff.md"},
+		sub:  "diff",
+		subOption: &ecspresso.DiffOption{
+			Unified:    true,
+			Output:     "text",
+			Format:     "markdown",
+			OutputFile: "diff.md",
 		},
 	},
 	{
@@ -693,6 +905,8 @@ var cliTests = []struct {
 		sub:  "diff",
 		subOption: &ecspresso.DiffOption{
 			Unified: true,
+			Output:  "text",
+			Format:  "text",
 		},
 		fn: func(t *testing.T, o any) {
 			if color.NoColor != true {
@@ -705,6 +919,8 @@ var cliTests = []struct {
 		sub:  "diff",
 		subOption: &ecspresso.DiffOption{
 			Unified: true,
+			Output:  "text",
+			Format:  "text",
 		},
 		fn: func(t *testing.T, o any) {
 			if color.NoColor == true {
@@ -718,6 +934,7 @@ var cliTests = []struct {
 		subOption: &ecspresso.AppSpecOption{
 			TaskDefinition: "latest",
 			UpdateService:  true,
+			Format:         "yaml",
 		},
 	},
 	{
@@ -726,33 +943,77 @@ var cliTests = []struct {
 		subOption: &ecspresso.AppSpecOption{
 			TaskDefinition: "current",
 			UpdateService:  false,
+			Format:         "yaml",
+		},
+	},
+	{
+		args: []string{"appspec", "--format", "json", "--s3", "s3://my-bucket/appspec.json"},
+		sub:  "appspec",
+		subOption: &ecspresso.AppSpecOption{
+			TaskDefinition: "latest",
+			UpdateService:  true,
+			Format:         "json",
+			S3:             "s3://my-bucket/appspec.json",
 		},
 	},
 	{
 		args: []string{"verify"},
 		sub:  "verify",
 		subOption: &ecspresso.VerifyOption{
-			GetSecrets: true,
-			PutLogs:    true,
-			Cache:      true,
+			GetSecrets:     true,
+			PutLogs:        true,
+			Cache:          true,
+			SimulatePolicy: true,
+			Output:         "text",
+			Parallel:       4,
 		},
 	},
 	{
 		args: []string{"verify", "--no-get-secrets", "--no-put-logs"},
 		sub:  "verify",
 		subOption: &ecspresso.VerifyOption{
-			GetSecrets: false,
-			PutLogs:    false,
-			Cache:      true,
+			GetSecrets:     false,
+			PutLogs:        false,
+			Cache:          true,
+			SimulatePolicy: true,
+			Output:         "text",
+			Parallel:       4,
 		},
 	},
 	{
 		args: []string{"verify", "--no-get-secrets", "--no-put-logs", "--no-cache"},
 		sub:  "verify",
 		subOption: &ecspresso.VerifyOption{
-			GetSecrets: false,
-			PutLogs:    false,
-			Cache:      false,
+			GetSecrets:     false,
+			PutLogs:        false,
+			Cache:          false,
+			SimulatePolicy: true,
+			Output:         "text",
+			Parallel:       4,
+		},
+	},
+	{
+		args: []string{"verify", "--output", "junit"},
+		sub:  "verify",
+		subOption: &ecspresso.VerifyOption{
+			GetSecrets:     true,
+			PutLogs:        true,
+			Cache:          true,
+			SimulatePolicy: true,
+			Output:         "junit",
+			Parallel:       4,
+		},
+	},
+	{
+		args: []string{"verify", "--no-simulate-policy"},
+		sub:  "verify",
+		subOption: &ecspresso.VerifyOption{
+			GetSecrets:     true,
+			PutLogs:        true,
+			Cache:          true,
+			SimulatePolicy: false,
+			Output:         "text",
+			Parallel:       4,
 		},
 	},
 	{
@@ -761,6 +1022,16 @@ var cliTests = []struct {
 		subOption: &ecspresso.RenderOption{
 			Targets: ptr([]string{"config", "taskdef", "servicedef"}),
 			Jsonnet: false,
+			Mask:    false,
+		},
+	},
+	{
+		args: []string{"render", "--mask", "taskdef"},
+		sub:  "render",
+		subOption: &ecspresso.RenderOption{
+			Targets: ptr([]string{"taskdef"}),
+			Jsonnet: false,
+			Mask:    true,
 		},
 	},
 	{
@@ -842,7 +1113,7 @@ func TestParseCLIv2(t *testing.T) {
 				}
 			}
 			if tt.subOption != nil {
-				if diff := cmp.Diff(opt.ForSubCommand(sub), tt.subOption, cmpopts.IgnoreUnexported(ecspresso.DiffOption{})); diff != "" {
+				if diff := cmp.Diff(opt.ForSubCommand(sub), tt.subOption, cmpopts.IgnoreUnexported(ecspresso.DiffOption{}, ecspresso.InitOption{}, ecspresso.RollbackOption{})); diff != "" {
 					t.Errorf("unexpected subOption: diff %s", diff)
 				}
 			}
@@ -862,6 +1133,7 @@ func CLIOptionsGlobalOnly(opts *ecspresso.CLIOptions) *ecspresso.CLIOptions {
 		Envfile:        opts.Envfile,
 		AssumeRoleARN:  opts.AssumeRoleARN,
 		Timeout:        opts.Timeout,
+		LockTimeout:    opts.LockTimeout,
 		FilterCommand:  opts.FilterCommand,
 	}
 }
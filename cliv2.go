@@ -23,7 +23,11 @@ func ParseCLIv2(args []string) (string, *CLIOptions, func(), error) {
 	if err != nil {
 		return "", nil, nil, fmt.Errorf("failed to parse args: %w", err)
 	}
-	sub := strings.Fields(c.Command())[0]
+	cmds := strings.Fields(c.Command())
+	sub := cmds[0]
+	if sub == "codedeploy" && len(cmds) > 1 {
+		opts.CodeDeploy.command = cmds[1]
+	}
 
 	for _, envFile := range opts.Envfile {
 		if err := ExportEnvFile(envFile); err != nil {
@@ -0,0 +1,113 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codedeploy"
+	cdTypes "github.com/aws/aws-sdk-go-v2/service/codedeploy/types"
+)
+
+// CodeDeployOption represents subcommands to control an in-progress deployment of CodeDeploy.
+type CodeDeployOption struct {
+	Continue *CodeDeployContinueOption `cmd:"" help:"reroute traffic to the replacement task set of the deployment waiting for manual reroute"`
+	Stop     *CodeDeployStopOption     `cmd:"" aliases:"abort" help:"stop the deployment"`
+	SkipWait *CodeDeploySkipWaitOption `cmd:"" help:"skip the wait for termination of the original task set"`
+
+	command string
+}
+
+type CodeDeployContinueOption struct {
+	Wait bool `help:"wait for the deployment completed" default:"true" negatable:""`
+}
+
+type CodeDeployStopOption struct {
+	Rollback bool `help:"roll back the deployment to the original task set" default:"true" negatable:""`
+}
+
+type CodeDeploySkipWaitOption struct {
+}
+
+func (d *App) CodeDeploy(ctx context.Context, opt CodeDeployOption) error {
+	ctx, cancel := d.Start(ctx)
+	defer cancel()
+
+	dpID, err := d.findInProgressDeploymentID(ctx)
+	if err != nil {
+		return err
+	}
+	out, err := d.codedeploy.GetDeployment(ctx, &codedeploy.GetDeploymentInput{
+		DeploymentId: aws.String(dpID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get deployment: %w", err)
+	}
+	dp := out.DeploymentInfo
+	d.Log("deployment id: %s status: %s", dpID, dp.Status)
+
+	switch opt.command {
+	case "continue":
+		return d.continueCodeDeploy(ctx, dp, *opt.Continue)
+	case "stop":
+		return d.stopCodeDeploy(ctx, dp, *opt.Stop)
+	case "skip-wait":
+		return d.skipWaitCodeDeploy(ctx, dp)
+	default:
+		return fmt.Errorf("unknown codedeploy command: %s", opt.command)
+	}
+}
+
+func (d *App) continueCodeDeploy(ctx context.Context, dp *cdTypes.DeploymentInfo, opt CodeDeployContinueOption) error {
+	if dp.Status != cdTypes.DeploymentStatusReady {
+		return fmt.Errorf("deployment %s is not waiting for traffic reroute. status: %s", aws.ToString(dp.DeploymentId), dp.Status)
+	}
+	d.Log("Rerouting traffic to the replacement task set")
+	if _, err := d.codedeploy.ContinueDeployment(ctx, &codedeploy.ContinueDeploymentInput{
+		DeploymentId:       dp.DeploymentId,
+		DeploymentWaitType: cdTypes.DeploymentWaitTypeReadyWait,
+	}); err != nil {
+		return fmt.Errorf("failed to continue deployment: %w", err)
+	}
+	if !opt.Wait {
+		d.Log("Traffic is rerouted.")
+		return nil
+	}
+	if err := d.WaitForCodeDeploy(ctx, nil); err != nil {
+		return err
+	}
+	d.Log("Deployment is completed!")
+	return nil
+}
+
+func (d *App) stopCodeDeploy(ctx context.Context, dp *cdTypes.DeploymentInfo, opt CodeDeployStopOption) error {
+	if opt.Rollback {
+		d.Log("Stopping the deployment with rollback")
+	} else {
+		d.Log("Stopping the deployment without rollback")
+	}
+	out, err := d.codedeploy.StopDeployment(ctx, &codedeploy.StopDeploymentInput{
+		DeploymentId:        dp.DeploymentId,
+		AutoRollbackEnabled: aws.Bool(opt.Rollback),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stop deployment: %w", err)
+	}
+	d.Log("Stop deployment: %s %s", out.Status, aws.ToString(out.StatusMessage))
+	return nil
+}
+
+func (d *App) skipWaitCodeDeploy(ctx context.Context, dp *cdTypes.DeploymentInfo) error {
+	if dp.Status != cdTypes.DeploymentStatusInProgress {
+		return fmt.Errorf("deployment %s is not in progress. status: %s", aws.ToString(dp.DeploymentId), dp.Status)
+	}
+	d.Log("Skipping the wait for termination of the original task set")
+	if _, err := d.codedeploy.ContinueDeployment(ctx, &codedeploy.ContinueDeploymentInput{
+		DeploymentId:       dp.DeploymentId,
+		DeploymentWaitType: cdTypes.DeploymentWaitTypeTerminationWait,
+	}); err != nil {
+		return fmt.Errorf("failed to skip the termination wait: %w", err)
+	}
+	d.Log("The original task set will be terminated.")
+	return nil
+}
@@ -0,0 +1,212 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codedeploy"
+	cdTypes "github.com/aws/aws-sdk-go-v2/service/codedeploy/types"
+)
+
+// DeploymentGroupDefinition represents a definition of CodeDeploy deployment group for the ECS service.
+// The application name and the deployment group name are taken from the codedeploy section of the config.
+type DeploymentGroupDefinition struct {
+	ServiceRoleArn                   *string
+	DeploymentConfigName             *string
+	DeploymentStyle                  *cdTypes.DeploymentStyle
+	BlueGreenDeploymentConfiguration *cdTypes.BlueGreenDeploymentConfiguration
+	LoadBalancerInfo                 *cdTypes.LoadBalancerInfo
+	AlarmConfiguration               *cdTypes.AlarmConfiguration
+	AutoRollbackConfiguration        *cdTypes.AutoRollbackConfiguration
+}
+
+func newDeploymentGroupDefinition(info *cdTypes.DeploymentGroupInfo) *DeploymentGroupDefinition {
+	dg := &DeploymentGroupDefinition{
+		ServiceRoleArn:                   info.ServiceRoleArn,
+		DeploymentConfigName:             info.DeploymentConfigName,
+		DeploymentStyle:                  info.DeploymentStyle,
+		BlueGreenDeploymentConfiguration: info.BlueGreenDeploymentConfiguration,
+		LoadBalancerInfo:                 info.LoadBalancerInfo,
+		AlarmConfiguration:               info.AlarmConfiguration,
+		AutoRollbackConfiguration:        info.AutoRollbackConfiguration,
+	}
+	dg.normalize()
+	return dg
+}
+
+// normalize removes disabled configurations and sorts elements to compare definitions.
+func (dg *DeploymentGroupDefinition) normalize() {
+	if dg == nil {
+		return
+	}
+	if c := dg.AlarmConfiguration; c != nil && !c.Enabled && len(c.Alarms) == 0 {
+		dg.AlarmConfiguration = nil
+	}
+	if c := dg.AlarmConfiguration; c != nil {
+		sort.SliceStable(c.Alarms, func(i, j int) bool {
+			return aws.ToString(c.Alarms[i].Name) < aws.ToString(c.Alarms[j].Name)
+		})
+	}
+	if c := dg.AutoRollbackConfiguration; c != nil && !c.Enabled && len(c.Events) == 0 {
+		dg.AutoRollbackConfiguration = nil
+	}
+	if c := dg.AutoRollbackConfiguration; c != nil {
+		sort.SliceStable(c.Events, func(i, j int) bool {
+			return c.Events[i] < c.Events[j]
+		})
+	}
+}
+
+// LoadDeploymentGroupDefinition loads a deployment group definition from path.
+func (d *App) LoadDeploymentGroupDefinition(path string) (*DeploymentGroupDefinition, error) {
+	if path == "" {
+		return nil, fmt.Errorf("codedeploy.deployment_group_definition is not defined")
+	}
+	var dg DeploymentGroupDefinition
+	src, err := d.readDefinitionFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deployment group definition %s: %w", path, err)
+	}
+	if err := unmarshalJSON(src, &dg, path); err != nil {
+		return nil, fmt.Errorf("failed to load deployment group definition %s: %w", path, err)
+	}
+	dg.normalize()
+	return &dg, nil
+}
+
+// describeDeploymentGroup returns the deployment group defined in the config.
+// It returns ErrNotFound when the application or the deployment group does not exist.
+func (d *App) describeDeploymentGroup(ctx context.Context) (*cdTypes.DeploymentGroupInfo, error) {
+	cd := d.config.CodeDeploy
+	out, err := d.codedeploy.GetDeploymentGroup(ctx, &codedeploy.GetDeploymentGroupInput{
+		ApplicationName:     aws.String(cd.ApplicationName),
+		DeploymentGroupName: aws.String(cd.DeploymentGroupName),
+	})
+	if err != nil {
+		var appNotFound *cdTypes.ApplicationDoesNotExistException
+		var dgNotFound *cdTypes.DeploymentGroupDoesNotExistException
+		if errors.As(err, &appNotFound) || errors.As(err, &dgNotFound) {
+			return nil, ErrNotFound(fmt.Sprintf("deployment group %s/%s is not found", cd.ApplicationName, cd.DeploymentGroupName))
+		}
+		return nil, fmt.Errorf("failed to get deployment group: %w", err)
+	}
+	return out.DeploymentGroupInfo, nil
+}
+
+// applyDeploymentGroup creates or updates the deployment group by the definition.
+func (d *App) applyDeploymentGroup(ctx context.Context, opt DeployOption) error {
+	cd := d.config.CodeDeploy
+	if cd == nil || cd.DeploymentGroupDefinitionPath == "" {
+		return nil
+	}
+	dg, err := d.LoadDeploymentGroupDefinition(cd.DeploymentGroupDefinitionPath)
+	if err != nil {
+		return err
+	}
+	var appNotFound bool
+	if _, err := d.codedeploy.GetApplication(ctx, &codedeploy.GetApplicationInput{
+		ApplicationName: aws.String(cd.ApplicationName),
+	}); err != nil {
+		var e *cdTypes.ApplicationDoesNotExistException
+		if !errors.As(err, &e) {
+			return fmt.Errorf("failed to get application: %w", err)
+		}
+		appNotFound = true
+	}
+	var remote *cdTypes.DeploymentGroupInfo
+	if !appNotFound {
+		if remote, err = d.describeDeploymentGroup(ctx); err != nil && !errors.As(err, &errNotFound) {
+			return err
+		}
+	}
+
+	if remote == nil {
+		d.Log("[INFO] Creating deployment group %s/%s %s", cd.ApplicationName, cd.DeploymentGroupName, opt.DryRunString())
+		if opt.DryRun {
+			d.OutputJSONForAPI(os.Stderr, dg)
+			return nil
+		}
+		if appNotFound {
+			d.Log("[INFO] Creating application %s", cd.ApplicationName)
+			if _, err := d.codedeploy.CreateApplication(ctx, &codedeploy.CreateApplicationInput{
+				ApplicationName: aws.String(cd.ApplicationName),
+				ComputePlatform: cdTypes.ComputePlatformEcs,
+			}); err != nil {
+				return fmt.Errorf("failed to create application: %w", err)
+			}
+		}
+		if _, err := d.codedeploy.CreateDeploymentGroup(ctx, &codedeploy.CreateDeploymentGroupInput{
+			ApplicationName:                  aws.String(cd.ApplicationName),
+			DeploymentGroupName:              aws.String(cd.DeploymentGroupName),
+			ServiceRoleArn:                   dg.ServiceRoleArn,
+			DeploymentConfigName:             dg.DeploymentConfigName,
+			DeploymentStyle:                  dg.DeploymentStyle,
+			BlueGreenDeploymentConfiguration: dg.BlueGreenDeploymentConfiguration,
+			LoadBalancerInfo:                 dg.LoadBalancerInfo,
+			AlarmConfiguration:               dg.AlarmConfiguration,
+			AutoRollbackConfiguration:        dg.AutoRollbackConfiguration,
+			EcsServices:                      d.deploymentGroupECSServices(),
+		}); err != nil {
+			return fmt.Errorf("failed to create deployment group: %w", err)
+		}
+		d.Log("Deployment group is created")
+		return nil
+	}
+
+	differ, err := diffDeploymentGroups(ctx, dg, newDeploymentGroupDefinition(remote), cd.DeploymentGroupDefinitionPath, d.deploymentGroupLabel(), &DiffOption{Unified: true, w: io.Discard})
+	if err != nil {
+		return fmt.Errorf("failed to diff of deployment group definitions: %w", err)
+	}
+	if !differ {
+		d.Log("deployment group will not change")
+		return nil
+	}
+	d.Log("[INFO] Updating deployment group %s/%s %s", cd.ApplicationName, cd.DeploymentGroupName, opt.DryRunString())
+	if opt.DryRun {
+		d.OutputJSONForAPI(os.Stderr, dg)
+		return nil
+	}
+	in := &codedeploy.UpdateDeploymentGroupInput{
+		ApplicationName:                  aws.String(cd.ApplicationName),
+		CurrentDeploymentGroupName:       aws.String(cd.DeploymentGroupName),
+		ServiceRoleArn:                   dg.ServiceRoleArn,
+		DeploymentConfigName:             dg.DeploymentConfigName,
+		DeploymentStyle:                  dg.DeploymentStyle,
+		BlueGreenDeploymentConfiguration: dg.BlueGreenDeploymentConfiguration,
+		LoadBalancerInfo:                 dg.LoadBalancerInfo,
+		AlarmConfiguration:               dg.AlarmConfiguration,
+		AutoRollbackConfiguration:        dg.AutoRollbackConfiguration,
+		EcsServices:                      d.deploymentGroupECSServices(),
+	}
+	// nil configurations are not changed by UpdateDeploymentGroup, so disable them explicitly.
+	if in.AlarmConfiguration == nil {
+		in.AlarmConfiguration = &cdTypes.AlarmConfiguration{Enabled: false}
+	}
+	if in.AutoRollbackConfiguration == nil {
+		in.AutoRollbackConfiguration = &cdTypes.AutoRollbackConfiguration{Enabled: false}
+	}
+	if _, err := d.codedeploy.UpdateDeploymentGroup(ctx, in); err != nil {
+		return fmt.Errorf("failed to update deployment group: %w", err)
+	}
+	d.Log("Deployment group is updated")
+	return nil
+}
+
+func (d *App) deploymentGroupECSServices() []cdTypes.ECSService {
+	return []cdTypes.ECSService{
+		{
+			ClusterName: aws.String(d.config.Cluster),
+			ServiceName: aws.String(d.config.Service),
+		},
+	}
+}
+
+func (d *App) deploymentGroupLabel() string {
+	cd := d.config.CodeDeploy
+	return fmt.Sprintf("codedeploy://%s/%s", cd.ApplicationName, cd.DeploymentGroupName)
+}
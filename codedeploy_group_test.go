@@ -0,0 +1,192 @@
+package ecspresso_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/codedeploy"
+	cdTypes "github.com/aws/aws-sdk-go-v2/service/codedeploy/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+// newCodeDeployGroupTestApp returns an App which has the remote deployment group (nil means not found).
+func newCodeDeployGroupTestApp(t *testing.T, remote *cdTypes.DeploymentGroupInfo) (*ecspresso.App, *sdkMock) {
+	results := map[string]any{
+		"GetApplication":        &codedeploy.GetApplicationOutput{},
+		"GetDeploymentGroup":    &codedeploy.GetDeploymentGroupOutput{DeploymentGroupInfo: remote},
+		"CreateApplication":     &codedeploy.CreateApplicationOutput{},
+		"CreateDeploymentGroup": &codedeploy.CreateDeploymentGroupOutput{},
+		"UpdateDeploymentGroup": &codedeploy.UpdateDeploymentGroupOutput{},
+	}
+	if remote == nil {
+		results["GetApplication"] = &cdTypes.ApplicationDoesNotExistException{}
+		results["GetDeploymentGroup"] = &cdTypes.DeploymentGroupDoesNotExistException{}
+	}
+	return newSDKMockApp(t, "tests/codedeploy_group/ecspresso.yml", results)
+}
+
+func remoteDeploymentGroup() *cdTypes.DeploymentGroupInfo {
+	return &cdTypes.DeploymentGroupInfo{
+		ApplicationName:      ptr("AppECS-default-test"),
+		DeploymentGroupName:  ptr("DgpECS-default-test"),
+		ServiceRoleArn:       ptr("arn:aws:iam::123456789012:role/ecsCodeDeployRole"),
+		DeploymentConfigName: ptr("CodeDeployDefault.ECSAllAtOnce"),
+		DeploymentStyle: &cdTypes.DeploymentStyle{
+			DeploymentOption: cdTypes.DeploymentOptionWithTrafficControl,
+			DeploymentType:   cdTypes.DeploymentTypeBlueGreen,
+		},
+		BlueGreenDeploymentConfiguration: &cdTypes.BlueGreenDeploymentConfiguration{
+			DeploymentReadyOption: &cdTypes.DeploymentReadyOption{
+				ActionOnTimeout: cdTypes.DeploymentReadyActionContinueDeployment,
+			},
+			TerminateBlueInstancesOnDeploymentSuccess: &cdTypes.BlueInstanceTerminationOption{
+				Action:                       cdTypes.InstanceActionTerminate,
+				TerminationWaitTimeInMinutes: 5,
+			},
+		},
+		LoadBalancerInfo: &cdTypes.LoadBalancerInfo{
+			TargetGroupPairInfoList: []cdTypes.TargetGroupPairInfo{
+				{
+					ProdTrafficRoute: &cdTypes.TrafficRoute{
+						ListenerArns: []string{"arn:aws:elasticloadbalancing:ap-northeast-1:123456789012:listener/app/test/0123456789abcdef/0123456789abcdef"},
+					},
+					TargetGroups: []cdTypes.TargetGroupInfo{
+						{Name: ptr("test-blue")},
+						{Name: ptr("test-green")},
+					},
+				},
+			},
+		},
+		// disabled configurations are returned by the API
+		AlarmConfiguration: &cdTypes.AlarmConfiguration{Enabled: false},
+		AutoRollbackConfiguration: &cdTypes.AutoRollbackConfiguration{
+			Enabled: true,
+			Events: []cdTypes.AutoRollbackEvent{
+				cdTypes.AutoRollbackEventDeploymentFailure,
+				cdTypes.AutoRollbackEventDeploymentStopOnAlarm,
+			},
+		},
+		EcsServices: []cdTypes.ECSService{
+			{ClusterName: ptr("default"), ServiceName: ptr("test")},
+		},
+	}
+}
+
+func TestLoadDeploymentGroupDefinition(t *testing.T) {
+	app, _ := newCodeDeployGroupTestApp(t, nil)
+	dg, err := app.LoadDeploymentGroupDefinition("tests/codedeploy_group/ecs-deployment-group.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := *dg.DeploymentConfigName; v != "CodeDeployDefault.ECSAllAtOnce" {
+		t.Errorf("unexpected deploymentConfigName %s", v)
+	}
+	if v := dg.DeploymentStyle.DeploymentType; v != cdTypes.DeploymentTypeBlueGreen {
+		t.Errorf("unexpected deploymentType %s", v)
+	}
+	if n := len(dg.LoadBalancerInfo.TargetGroupPairInfoList[0].TargetGroups); n != 2 {
+		t.Errorf("unexpected number of target groups %d", n)
+	}
+	// events are sorted
+	if v := dg.AutoRollbackConfiguration.Events[0]; v != cdTypes.AutoRollbackEventDeploymentFailure {
+		t.Errorf("unexpected first event %s", v)
+	}
+}
+
+func TestDeploymentGroupDefinitionRequiresNames(t *testing.T) {
+	_, err := ecspresso.New(context.Background(), &ecspresso.CLIOptions{ConfigFilePath: "tests/codedeploy_group/invalid.yml"})
+	if err == nil {
+		t.Fatal("expected error for deployment_group_definition without names")
+	}
+}
+
+func TestDiffDeploymentGroup(t *testing.T) {
+	ctx := context.Background()
+	t.Run("no changes", func(t *testing.T) {
+		app, _ := newCodeDeployGroupTestApp(t, remoteDeploymentGroup())
+		b := &bytes.Buffer{}
+		opt := &ecspresso.DiffOption{}
+		opt.SetWriter(b)
+		if err := app.DiffDeploymentGroup(ctx, opt); err != nil {
+			t.Fatal(err)
+		}
+		if b.Len() != 0 {
+			t.Errorf("unexpected diff %s", b.String())
+		}
+	})
+	t.Run("changed", func(t *testing.T) {
+		remote := remoteDeploymentGroup()
+		remote.DeploymentConfigName = ptr("CodeDeployDefault.ECSLinear10PercentEvery1Minutes")
+		app, _ := newCodeDeployGroupTestApp(t, remote)
+		b := &bytes.Buffer{}
+		opt := &ecspresso.DiffOption{}
+		opt.SetWriter(b)
+		if err := app.DiffDeploymentGroup(ctx, opt); err != nil {
+			t.Fatal(err)
+		}
+		if s := b.String(); !strings.Contains(s, "CodeDeployDefault.ECSLinear10PercentEvery1Minutes") || !strings.Contains(s, "codedeploy://AppECS-default-test/DgpECS-default-test") {
+			t.Errorf("unexpected diff %s", s)
+		}
+	})
+}
+
+func TestApplyDeploymentGroup(t *testing.T) {
+	ctx := context.Background()
+	t.Run("create", func(t *testing.T) {
+		app, m := newCodeDeployGroupTestApp(t, nil)
+		if err := app.ApplyDeploymentGroup(ctx, ecspresso.DeployOption{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m.called("CreateApplication"); !ok {
+			t.Error("CreateApplication is not called")
+		}
+		v, ok := m.called("CreateDeploymentGroup")
+		if !ok {
+			t.Fatal("CreateDeploymentGroup is not called")
+		}
+		in := v.(*codedeploy.CreateDeploymentGroupInput)
+		if *in.DeploymentGroupName != "DgpECS-default-test" || *in.EcsServices[0].ServiceName != "test" {
+			t.Errorf("unexpected input %#v", in)
+		}
+	})
+	t.Run("create dry run", func(t *testing.T) {
+		app, m := newCodeDeployGroupTestApp(t, nil)
+		if err := app.ApplyDeploymentGroup(ctx, ecspresso.DeployOption{DryRun: true}); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m.called("CreateDeploymentGroup"); ok {
+			t.Error("CreateDeploymentGroup is called in dry run")
+		}
+	})
+	t.Run("no changes", func(t *testing.T) {
+		app, m := newCodeDeployGroupTestApp(t, remoteDeploymentGroup())
+		if err := app.ApplyDeploymentGroup(ctx, ecspresso.DeployOption{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m.called("UpdateDeploymentGroup"); ok {
+			t.Error("UpdateDeploymentGroup is called without changes")
+		}
+	})
+	t.Run("update", func(t *testing.T) {
+		remote := remoteDeploymentGroup()
+		remote.AlarmConfiguration = &cdTypes.AlarmConfiguration{
+			Enabled: true,
+			Alarms:  []cdTypes.Alarm{{Name: ptr("test-5xx")}},
+		}
+		app, m := newCodeDeployGroupTestApp(t, remote)
+		if err := app.ApplyDeploymentGroup(ctx, ecspresso.DeployOption{}); err != nil {
+			t.Fatal(err)
+		}
+		v, ok := m.called("UpdateDeploymentGroup")
+		if !ok {
+			t.Fatal("UpdateDeploymentGroup is not called")
+		}
+		in := v.(*codedeploy.UpdateDeploymentGroupInput)
+		// alarm configuration is not defined in the definition, so it is disabled
+		if in.AlarmConfiguration == nil || in.AlarmConfiguration.Enabled {
+			t.Errorf("alarm configuration should be disabled %#v", in.AlarmConfiguration)
+		}
+	})
+}
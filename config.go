@@ -51,18 +51,29 @@ func newConfigLoader(extStr, extCode map[string]string) *configLoader {
 
 // Config represents a configuration.
 type Config struct {
-	RequiredVersion       string            `yaml:"required_version,omitempty" json:"required_version,omitempty"`
-	Region                string            `yaml:"region" json:"region"`
-	Cluster               string            `yaml:"cluster" json:"cluster"`
-	Service               string            `yaml:"service" json:"service"`
-	ServiceDefinitionPath string            `yaml:"service_definition" json:"service_definition"`
-	TaskDefinitionPath    string            `yaml:"task_definition" json:"task_definition"`
-	Plugins               []ConfigPlugin    `yaml:"plugins,omitempty" json:"plugins,omitempty"`
-	AppSpec               *appspec.AppSpec  `yaml:"appspec,omitempty" json:"appspec,omitempty"`
-	FilterCommand         string            `yaml:"filter_command,omitempty" json:"filter_command,omitempty"`
-	Timeout               *Duration         `yaml:"timeout,omitempty" json:"timeout,omitempty"`
-	CodeDeploy            *ConfigCodeDeploy `yaml:"codedeploy,omitempty" json:"codedeploy,omitempty"`
-	Ignore                *ConfigIgnore     `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+	RequiredVersion       string                `yaml:"required_version,omitempty" json:"required_version,omitempty"`
+	Region                string                `yaml:"region" json:"region"`
+	Cluster               string                `yaml:"cluster" json:"cluster"`
+	Service               string                `yaml:"service" json:"service"`
+	ServiceDefinitionPath string                `yaml:"service_definition" json:"service_definition"`
+	TaskDefinitionPath    string                `yaml:"task_definition" json:"task_definition"`
+	Plugins               []ConfigPlugin        `yaml:"plugins,omitempty" json:"plugins,omitempty"`
+	AppSpec               *appspec.AppSpec      `yaml:"appspec,omitempty" json:"appspec,omitempty"`
+	FilterCommand         string                `yaml:"filter_command,omitempty" json:"filter_command,omitempty"`
+	Timeout               *Duration             `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	CodeDeploy            *ConfigCodeDeploy     `yaml:"codedeploy,omitempty" json:"codedeploy,omitempty"`
+	Ignore                *ConfigIgnore         `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+	Tags                  *ConfigTags           `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Verify                *ConfigVerify         `yaml:"verify,omitempty" json:"verify,omitempty"`
+	Mask                  *ConfigMask           `yaml:"mask,omitempty" json:"mask,omitempty"`
+	DeletionProtection    bool                  `yaml:"deletion_protection,omitempty" json:"deletion_protection,omitempty"`
+	Lock                  *ConfigLock           `yaml:"lock,omitempty" json:"lock,omitempty"`
+	Audit                 *ConfigAudit          `yaml:"audit,omitempty" json:"audit,omitempty"`
+	Hooks                 *ConfigHooks          `yaml:"hooks,omitempty" json:"hooks,omitempty"`
+	SmokeTest             *ConfigSmokeTest      `yaml:"smoke_test,omitempty" json:"smoke_test,omitempty"`
+	AlarmWatch            *ConfigAlarmWatch     `yaml:"alarm_watch,omitempty" json:"alarm_watch,omitempty"`
+	TaskSet               *ConfigTaskSet        `yaml:"task_set,omitempty" json:"task_set,omitempty"`
+	Notifications         []*ConfigNotification `yaml:"notifications,omitempty" json:"notifications,omitempty"`
 
 	path               string
 	templateFuncs      []template.FuncMap
@@ -70,11 +81,26 @@ type Config struct {
 	dir                string
 	versionConstraints goVersion.Constraints
 	awsv2Config        aws.Config
+	masker             *secretMasker
 }
 
 type ConfigCodeDeploy struct {
-	ApplicationName     string `yaml:"application_name,omitempty" json:"application_name,omitempty"`
-	DeploymentGroupName string `yaml:"deployment_group_name,omitempty" json:"deployment_group_name,omitempty"`
+	ApplicationName               string `yaml:"application_name,omitempty" json:"application_name,omitempty"`
+	DeploymentGroupName           string `yaml:"deployment_group_name,omitempty" json:"deployment_group_name,omitempty"`
+	DeploymentGroupDefinitionPath string `yaml:"deployment_group_definition,omitempty" json:"deployment_group_definition,omitempty"`
+}
+
+func (c *ConfigCodeDeploy) setup(dir string) error {
+	if c == nil || c.DeploymentGroupDefinitionPath == "" {
+		return nil
+	}
+	if c.ApplicationName == "" || c.DeploymentGroupName == "" {
+		return fmt.Errorf("codedeploy: application_name and deployment_group_name are required for deployment_group_definition")
+	}
+	if !filepath.IsAbs(c.DeploymentGroupDefinitionPath) {
+		c.DeploymentGroupDefinitionPath = filepath.Join(dir, c.DeploymentGroupDefinitionPath)
+	}
+	return nil
 }
 
 // Load loads configuration file from file path.
@@ -129,6 +155,9 @@ func (c *Config) OverrideByCLIOptions(opt *CLIOptions) {
 	if opt.FilterCommand != "" {
 		c.FilterCommand = opt.FilterCommand
 	}
+	if opt.LockTimeout != nil && c.Lock != nil {
+		c.Lock.Timeout = &Duration{*opt.LockTimeout}
+	}
 }
 
 // Restrict restricts a configuration.
@@ -159,6 +188,40 @@ func (c *Config) Restrict(ctx context.Context) error {
 		c.Region = os.Getenv("AWS_REGION")
 	}
 	var err error
+	if c.masker, err = newSecretMasker(c.Mask); err != nil {
+		return err
+	}
+	if c.AppSpec != nil {
+		if err := c.AppSpec.Validate(); err != nil {
+			return fmt.Errorf("appspec: %w", err)
+		}
+	}
+	if err := c.CodeDeploy.setup(c.dir); err != nil {
+		return err
+	}
+	if err := c.Lock.validate(); err != nil {
+		return err
+	}
+	if err := c.Audit.validate(); err != nil {
+		return err
+	}
+	if err := c.Hooks.validate(); err != nil {
+		return err
+	}
+	if err := c.AlarmWatch.setup(); err != nil {
+		return err
+	}
+	if err := c.TaskSet.setup(); err != nil {
+		return err
+	}
+	if err := c.SmokeTest.setup(c.dir); err != nil {
+		return err
+	}
+	for _, n := range c.Notifications {
+		if err := n.setup(c.dir); err != nil {
+			return err
+		}
+	}
 	var optsFunc []func(*awsConfig.LoadOptions) error
 	if len(awsv2ConfigLoadOptionsFunc) == 0 {
 		// default
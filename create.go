@@ -22,6 +22,14 @@ func (d *App) createService(ctx context.Context, opt DeployOption) error {
 	if err != nil {
 		return err
 	}
+	if err := d.checkTagPolicy("service", svd.Tags); err != nil {
+		return err
+	}
+	if !opt.LatestTaskDefinition && !opt.SkipTaskDefinition {
+		if err := d.checkTagPolicy("task definition", td.Tags); err != nil {
+			return err
+		}
+	}
 
 	count := calcDesiredCount(svd, opt)
 	if count == nil && (svd.SchedulingStrategy != "" && svd.SchedulingStrategy == types.SchedulingStrategyReplica) {
@@ -33,6 +41,11 @@ func (d *App) createService(ctx context.Context, opt DeployOption) error {
 		d.OutputJSONForAPI(os.Stderr, td)
 		d.Log("service definition:")
 		d.OutputJSONForAPI(os.Stderr, svd)
+		if svd.isCodeDeploy() {
+			if err := d.applyDeploymentGroup(ctx, opt); err != nil {
+				return err
+			}
+		}
 		d.Log("DRY RUN OK")
 		return nil
 	}
@@ -46,12 +59,25 @@ func (d *App) createService(ctx context.Context, opt DeployOption) error {
 		}
 		d.Log("Using latest task definition %s", tdArn)
 	} else {
+		if err := d.runHooks(ctx, hookPreRegister); err != nil {
+			return err
+		}
 		newTd, err := d.RegisterTaskDefinition(ctx, td)
 		if err != nil {
 			return err
 		}
 		tdArn = *newTd.TaskDefinitionArn
 	}
+	recordTaskDefinition(ctx, "", tdArn)
+	d.notifyStarted(ctx, eventDeployStarted)
+	if opt.PreRun != "" {
+		if err := d.preRunTask(ctx, tdArn, opt); err != nil {
+			return err
+		}
+	}
+	if err := d.runHooks(ctx, hookPreDeploy); err != nil {
+		return err
+	}
 
 	createServiceInput := &ecs.CreateServiceInput{
 		Cluster:                       aws.String(d.config.Cluster),
@@ -77,10 +103,31 @@ func (d *App) createService(ctx context.Context, opt DeployOption) error {
 		TaskDefinition:                aws.String(tdArn),
 		VolumeConfigurations:          svd.VolumeConfigurations,
 	}
+	if svd.isExternal() {
+		// these attributes are defined by task sets
+		createServiceInput.CapacityProviderStrategy = nil
+		createServiceInput.LaunchType = ""
+		createServiceInput.LoadBalancers = nil
+		createServiceInput.NetworkConfiguration = nil
+		createServiceInput.PlatformVersion = nil
+		createServiceInput.ServiceRegistries = nil
+		createServiceInput.TaskDefinition = nil
+	}
 	if _, err := d.ecs.CreateService(ctx, createServiceInput); err != nil {
 		return fmt.Errorf("failed to create service: %w", err)
 	}
 	d.Log("Service is created")
+	if svd.isExternal() {
+		if err := d.shiftTaskSet(ctx, d.newCreateTaskSetInput(svd, nil, tdArn), nil, opt.Wait); err != nil {
+			return err
+		}
+	}
+	// the deployment group requires the service to be created
+	if svd.isCodeDeploy() {
+		if err := d.applyDeploymentGroup(ctx, opt); err != nil {
+			return err
+		}
+	}
 
 	if !opt.Wait {
 		return nil
@@ -2,16 +2,25 @@ package ecspresso
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Songmu/prompter"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 )
 
 type DeleteOption struct {
-	DryRun    bool `help:"dry-run" default:"false"`
-	Force     bool `help:"delete without confirmation" default:"false"`
-	Terminate bool `help:"delete with terminate tasks" default:"false"`
+	DryRun                    bool   `help:"dry-run" default:"false"`
+	Force                     bool   `help:"delete without confirmation" default:"false"`
+	Terminate                 bool   `help:"delete with terminate tasks" default:"false"`
+	IgnoreDeletionProtection  bool   `help:"delete the service even if deletion_protection is enabled in the config" default:"false"`
+	ScaleToZero               bool   `help:"scale the service to zero and wait for tasks to be drained before deleting" default:"false"`
+	DeregisterTaskDefinitions bool   `help:"deregister all revisions of the task definition family after deleting" default:"false"`
+	Snapshot                  string `help:"directory to save the config, service and task definition files of the service before deleting" default:""`
 }
 
 func (opt DeleteOption) DryRunString() string {
@@ -25,13 +34,31 @@ func (d *App) Delete(ctx context.Context, opt DeleteOption) error {
 	ctx, cancel := d.Start(ctx)
 	defer cancel()
 
+	if d.config.DeletionProtection {
+		if !opt.IgnoreDeletionProtection {
+			return errors.New("deletion_protection is enabled in the config. To delete the service, use --ignore-deletion-protection")
+		}
+		d.Log("[WARNING] deletion_protection is enabled in the config, but it is ignored")
+	}
+
 	d.Log("Deleting service %s", opt.DryRunString())
 	sv, err := d.DescribeServiceStatus(ctx, 3)
 	if err != nil {
 		return err
 	}
 
+	family := strings.Split(arnToName(aws.ToString(sv.TaskDefinition)), ":")[0]
+	recordTaskDefinition(ctx, aws.ToString(sv.TaskDefinition), "")
 	if opt.DryRun {
+		if opt.Snapshot != "" {
+			d.Log("snapshot of the service will be saved to %s", opt.Snapshot)
+		}
+		if opt.ScaleToZero {
+			d.Log("service will be scaled to zero")
+		}
+		if opt.DeregisterTaskDefinitions {
+			d.Log("all revisions of the task definition family %s will be deregistered", family)
+		}
 		d.Log("DRY RUN OK")
 		return nil
 	}
@@ -43,6 +70,28 @@ func (d *App) Delete(ctx context.Context, opt DeleteOption) error {
 			return fmt.Errorf("confirmation failed")
 		}
 	}
+
+	if opt.Snapshot != "" {
+		if err := d.saveSnapshot(ctx, opt.Snapshot); err != nil {
+			return err
+		}
+	}
+
+	if opt.ScaleToZero && aws.ToInt32(sv.DesiredCount) > 0 {
+		d.Log("Scaling the service to zero")
+		if _, err := d.ecs.UpdateService(ctx, &ecs.UpdateServiceInput{
+			Cluster:      &d.config.Cluster,
+			Service:      sv.ServiceName,
+			DesiredCount: aws.Int32(0),
+		}); err != nil {
+			return fmt.Errorf("failed to scale the service to zero: %w", err)
+		}
+		time.Sleep(delayForServiceChanged) // wait for service updated
+		if err := d.WaitServiceStable(ctx, sv); err != nil {
+			return err
+		}
+	}
+
 	dsi := &ecs.DeleteServiceInput{
 		Cluster: &d.config.Cluster,
 		Service: sv.ServiceName,
@@ -53,5 +102,41 @@ func (d *App) Delete(ctx context.Context, opt DeleteOption) error {
 	}
 	d.Log("Service is deleted")
 
+	if opt.DeregisterTaskDefinitions {
+		if err := d.deregisterFamily(ctx, family); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deregisterFamily deregisters all ACTIVE revisions of the task definition family.
+func (d *App) deregisterFamily(ctx context.Context, family string) error {
+	var names []string
+	p := ecs.NewListTaskDefinitionsPaginator(d.ecs, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Status:       types.TaskDefinitionStatusActive,
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list task definitions: %w", err)
+		}
+		for _, a := range out.TaskDefinitionArns {
+			if name, err := taskDefinitionToName(a); err == nil {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range names {
+		d.Log("Deregistring %s", name)
+		if _, err := d.ecs.DeregisterTaskDefinition(ctx, &ecs.DeregisterTaskDefinitionInput{
+			TaskDefinition: aws.String(name),
+		}); err != nil {
+			return fmt.Errorf("failed to deregister task definition: %w", err)
+		}
+		time.Sleep(time.Second)
+	}
+	d.Log("%d task definitions were deregistered", len(names))
 	return nil
 }
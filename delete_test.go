@@ -0,0 +1,30 @@
+package ecspresso_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestDeleteDeletionProtection(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/deletion_protection.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !app.Config().DeletionProtection {
+		t.Fatal("deletion_protection must be enabled")
+	}
+	for _, opt := range []ecspresso.DeleteOption{
+		{DryRun: true},
+		{Force: true},
+		{Force: true, Terminate: true},
+	} {
+		err := app.Delete(ctx, opt)
+		if err == nil || !strings.Contains(err.Error(), "deletion_protection") {
+			t.Errorf("%#v: expected deletion_protection error, got %v", opt, err)
+		}
+	}
+}
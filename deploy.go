@@ -39,6 +39,9 @@ type DeployOption struct {
 	RollbackEvents       string `help:"roll back when specified events happened (DEPLOYMENT_FAILURE,DEPLOYMENT_STOP_ON_ALARM,DEPLOYMENT_STOP_ON_REQUEST,...) CodeDeploy only." default:""`
 	UpdateService        bool   `help:"update service attributes by service definition" default:"true" negatable:""`
 	LatestTaskDefinition bool   `help:"deploy with the latest task definition without registering a new task definition" default:"false"`
+	PreRun               string `help:"task overrides file (JSON or Jsonnet) of a one-off task to run with the task definition before updating the service" default:""`
+	PreRunWatchContainer string `help:"container name for watching exit code of the pre-run task" default:""`
+	SmokeTest            bool   `help:"run the smoke test after the service is stable (if configured)" default:"true" negatable:""`
 }
 
 func (opt DeployOption) DryRunString() string {
@@ -75,15 +78,40 @@ func calcDesiredCount(sv *Service, opt DeployOption) *int32 {
 	return nil
 }
 
-func (d *App) Deploy(ctx context.Context, opt DeployOption) error {
+func (d *App) Deploy(ctx context.Context, opt DeployOption) (err error) {
 	d.Log("[DEBUG] deploy")
 	d.LogJSON(opt)
 	ctx, cancel := d.Start(ctx)
 	defer cancel()
 
+	if !opt.DryRun {
+		// do not shadow err, which is referred by the deferred notifications and hooks
+		unlock, lerr := d.acquireLock(ctx, "deploy")
+		if lerr != nil {
+			return lerr
+		}
+		defer unlock()
+
+		var n *Notification
+		ctx, n = d.startNotification(ctx)
+		defer func() {
+			d.notifyStarted(ctx, eventDeployStarted)
+			d.notifyResult(ctx, n, err, eventDeploySucceeded, eventDeployFailed)
+		}()
+
+		ctx, _ = d.startHooks(ctx, "deploy")
+		defer func() {
+			if err != nil {
+				d.runFailureHooks(ctx, err)
+			} else {
+				d.runHooksWithWarning(ctx, hookPostDeploy)
+			}
+		}()
+	}
+
 	var sv *Service
 	d.Log("Starting deploy %s", opt.DryRunString())
-	sv, err := d.DescribeServiceStatus(ctx, 0)
+	sv, err = d.DescribeServiceStatus(ctx, 0)
 	if err != nil {
 		if errors.As(err, &errNotFound) {
 			d.Log("Service %s not found. Creating a new service %s", d.Service, opt.DryRunString())
@@ -92,6 +120,8 @@ func (d *App) Deploy(ctx context.Context, opt DeployOption) error {
 		return err
 	}
 
+	hookContextFromContext(ctx).setTaskDefinition(aws.ToString(sv.TaskDefinition), "")
+
 	doDeploy, err := d.DeployFunc(sv)
 	if err != nil {
 		return err
@@ -101,23 +131,67 @@ func (d *App) Deploy(ctx context.Context, opt DeployOption) error {
 	if err != nil {
 		return err
 	}
+	recordTaskDefinition(ctx, aws.ToString(sv.TaskDefinition), tdArn)
+	d.addTaskDefinitionDiffSummary(ctx, auditRecordFromContext(ctx), aws.ToString(sv.TaskDefinition), tdArn)
+	if !opt.DryRun {
+		d.notifyStarted(ctx, eventDeployStarted)
+	}
+	if opt.PreRun != "" {
+		// the service is updated only if the pre-run task succeeded
+		if err := d.preRunTask(ctx, tdArn, opt); err != nil {
+			return err
+		}
+	}
+	if !opt.DryRun {
+		// the service is not changed if the pre_deploy hook failed
+		if err := d.runHooks(ctx, hookPreDeploy); err != nil {
+			return err
+		}
+	}
 
 	doWait, err := d.WaitFunc(sv, d.confirmPrimaryTD(tdArn))
 	if err != nil {
 		return err
 	}
+	if d.config.AlarmWatch != nil {
+		if sv.isCodeDeploy() {
+			d.Log("[WARNING] alarm_watch is not supported for CodeDeploy deployment controller")
+		} else if !opt.Wait {
+			d.Log("[WARNING] alarm_watch is ignored with --no-wait")
+		} else if !opt.DryRun {
+			// fail fast before changing the service, because the deployment would be rolled back soon
+			if err := d.checkAlarms(ctx); err != nil {
+				return err
+			}
+			doWait = d.alarmWatchWaitFunc(doWait, aws.ToString(sv.TaskDefinition))
+		}
+	}
 
 	var count *int32
+	// the tasks may not be placed by the new task definition or the new service attributes
+	tdChanged := tdArn != aws.ToString(sv.TaskDefinition)
 	if d.config.ServiceDefinitionPath != "" && opt.UpdateService {
 		newSv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
 		if err != nil {
 			return err
 		}
+		if err := d.checkTagPolicy("service", newSv.Tags); err != nil {
+			return err
+		}
 		addedTags, updatedTags, deletedTags := CompareTags(sv.Tags, newSv.Tags)
 		differ, err := diffServices(ctx, newSv, sv, d.config.ServiceDefinitionPath, &DiffOption{Unified: true, w: io.Discard})
 		if err != nil {
 			return fmt.Errorf("failed to diff of service definitions: %w", err)
 		}
+		if r := auditRecordFromContext(ctx); r != nil && differ {
+			sopt := &DiffOption{Output: "json", w: io.Discard}
+			if _, err := diffServices(ctx, newSv, sv, d.config.ServiceDefinitionPath, sopt); err == nil {
+				r.addDiffSummary(sopt.changes)
+			}
+		}
+		if differ || tdChanged {
+			d.warnPlacement(ctx, newSv, tdArn)
+		}
 		if differ {
 			if err = d.UpdateServiceAttributes(ctx, newSv, tdArn, opt); err != nil {
 				return err
@@ -131,6 +205,9 @@ func (d *App) Deploy(ctx context.Context, opt DeployOption) error {
 		}
 		count = calcDesiredCount(newSv, opt)
 	} else {
+		if tdChanged {
+			d.warnPlacement(ctx, sv, tdArn)
+		}
 		count = calcDesiredCount(sv, opt)
 	}
 	if count != nil {
@@ -144,6 +221,13 @@ func (d *App) Deploy(ctx context.Context, opt DeployOption) error {
 		return err
 	}
 
+	// manage deployment group of CodeDeploy
+	if sv.isCodeDeploy() {
+		if err := d.applyDeploymentGroup(ctx, opt); err != nil {
+			return err
+		}
+	}
+
 	if opt.DryRun {
 		d.Log("DRY RUN OK")
 		return nil
@@ -168,7 +252,7 @@ func (d *App) Deploy(ctx context.Context, opt DeployOption) error {
 	}
 
 	d.Log("Service is stable now. Completed!")
-	return nil
+	return d.runSmokeTest(ctx, tdArn, sv, opt)
 }
 
 func (d *App) UpdateServiceTasks(ctx context.Context, taskDefinitionArn string, count *int32, sv *Service, opt DeployOption) error {
@@ -221,9 +305,13 @@ func svToUpdateServiceInput(sv *Service) *ecs.UpdateServiceInput {
 
 func (d *App) UpdateServiceAttributes(ctx context.Context, sv *Service, taskDefinitionArn string, opt DeployOption) error {
 	in := svToUpdateServiceInput(sv)
-	if sv.isCodeDeploy() {
-		d.Log("[INFO] deployment by CodeDeploy")
-		// unable to update attributes below with a CODE_DEPLOY deployment controller.
+	if sv.isCodeDeploy() || sv.isExternal() {
+		if sv.isCodeDeploy() {
+			d.Log("[INFO] deployment by CodeDeploy")
+		} else {
+			d.Log("[INFO] deployment by task sets")
+		}
+		// unable to update attributes below with CODE_DEPLOY and EXTERNAL deployment controllers.
 		in.NetworkConfiguration = nil
 		in.PlatformVersion = nil
 		in.ForceNewDeployment = false
@@ -231,6 +319,13 @@ func (d *App) UpdateServiceAttributes(ctx context.Context, sv *Service, taskDefi
 		in.ServiceRegistries = nil
 		in.TaskDefinition = nil
 		in.CapacityProviderStrategy = nil
+		if sv.isExternal() {
+			// these attributes are also not supported with an EXTERNAL deployment controller.
+			in.DeploymentConfiguration = nil
+			in.EnableExecuteCommand = nil
+			in.ServiceConnectConfiguration = nil
+			in.VolumeConfigurations = nil
+		}
 	} else {
 		d.Log("[INFO] deployment by ECS rolling update")
 		in.ForceNewDeployment = opt.ForceNewDeployment
@@ -468,6 +563,8 @@ func (d *App) DeployFunc(sv *Service) (deployFunc, error) {
 			return d.DeployByCodeDeploy, nil
 		case types.DeploymentControllerTypeEcs:
 			return d.UpdateServiceTasks, nil
+		case types.DeploymentControllerTypeExternal:
+			return d.DeployByTaskSet, nil
 		default:
 			return nil, fmt.Errorf("unsupported deployment controller type: %s", dc.Type)
 		}
@@ -545,6 +642,9 @@ func (d *App) taskDefinitionArnForDeploy(ctx context.Context, sv *Service, opt D
 	if err != nil {
 		return "", err
 	}
+	if err := d.checkTagPolicy("task definition", td.Tags); err != nil {
+		return "", err
+	}
 
 	if opt.DryRun {
 		d.Log("[INFO] task definition:")
@@ -552,6 +652,9 @@ func (d *App) taskDefinitionArnForDeploy(ctx context.Context, sv *Service, opt D
 		return "", nil
 	}
 
+	if err := d.runHooks(ctx, hookPreRegister); err != nil {
+		return "", err
+	}
 	newTd, err := d.RegisterTaskDefinition(ctx, td)
 	if err != nil {
 		return "", err
@@ -22,45 +22,105 @@ import (
 	"github.com/hexops/gotextdiff/myers"
 	"github.com/hexops/gotextdiff/span"
 	"github.com/kylelemons/godebug/diff"
+	isatty "github.com/mattn/go-isatty"
 	"github.com/mattn/go-shellwords"
 )
 
 type DiffOption struct {
-	Unified  bool   `help:"unified diff format" default:"true" negatable:""`
-	External string `help:"external command to format diff" env:"ECSPRESSO_DIFF_COMMAND"`
+	Unified    bool   `help:"unified diff format" default:"true" negatable:""`
+	External   string `help:"external command to format diff" env:"ECSPRESSO_DIFF_COMMAND"`
+	Output     string `help:"output format (text, json)" default:"text" enum:"text,json"`
+	Format     string `help:"diff format (text, markdown)" default:"text" enum:"text,markdown"`
+	OutputFile string `help:"write diff to the file instead of STDOUT"`
+
+	Revision      int64  `help:"compare with the revision of the task definition instead of the current one" default:"0"`
+	ToRevision    int64  `help:"compare with the revision of the task definition instead of the local file" default:"0"`
+	CompareConfig string `help:"compare with the definitions of the other config file instead of the remote ones"`
+
+	w        io.Writer     `kong:"-"`
+	noColor  bool          `kong:"-"`
+	changes  []DiffChange  `kong:"-"`
+	masker   *secretMasker `kong:"-"`
+	sections []diffSection `kong:"-"`
+}
 
-	w io.Writer `kong:"-"`
+func (opt *DiffOption) isJSON() bool {
+	return opt.Output == "json"
 }
 
 func (d *App) Diff(ctx context.Context, opt DiffOption) error {
+	return d.diff(ctx, nil, opt)
+}
+
+// DiffConfig shows differences between definitions of the app and the other app loaded from another config file.
+func (d *App) DiffConfig(ctx context.Context, other *App, opt DiffOption) error {
+	return d.diff(ctx, other, opt)
+}
+
+func (d *App) diff(ctx context.Context, other *App, opt DiffOption) error {
 	ctx, cancel := d.Start(ctx)
 	defer cancel()
+	if opt.isJSON() && opt.isMarkdown() {
+		return ErrConflictOptions("--output json and --format markdown are exclusive")
+	}
+	if other != nil && (opt.Revision > 0 || opt.ToRevision > 0) {
+		return ErrConflictOptions("--compare-config and --revision/--to-revision are exclusive")
+	}
+	if opt.OutputFile != "" {
+		f, err := os.Create(opt.OutputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer f.Close()
+		opt.w = f
+	}
 	if opt.w == nil {
 		opt.w = os.Stdout
 	}
+	// escape sequences are garbage in files and pipes
+	if f, ok := opt.w.(*os.File); !ok || !isatty.IsTerminal(f.Fd()) {
+		opt.noColor = true
+	}
+	opt.masker = d.config.masker
 
 	var remoteTaskDefArn string
 	// diff for services only when service defined
-	if d.config.Service != "" {
-		d.Log("[DEBUG] diff service compare with %s", d.config.Service)
+	// two revisions of task definitions have no services to compare
+	if d.config.Service != "" && opt.ToRevision == 0 {
 		newSv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
 		if err != nil {
 			return fmt.Errorf("failed to load service definition: %w", err)
 		}
-		remoteSv, err := d.DescribeService(ctx)
-		if err != nil {
-			if errors.As(err, &errNotFound) {
-				d.Log("[INFO] service not found, will create a new service")
-			} else {
-				return fmt.Errorf("failed to describe service: %w", err)
+		if err := d.checkTagPolicy("service", newSv.Tags); err != nil {
+			d.Log("[WARNING] %s", err)
+		}
+		var remoteSv *Service
+		if other != nil {
+			d.Log("[DEBUG] diff service compare with %s", other.config.ServiceDefinitionPath)
+			if other.config.ServiceDefinitionPath != "" {
+				if remoteSv, err = other.LoadServiceDefinition(other.config.ServiceDefinitionPath); err != nil {
+					return fmt.Errorf("failed to load service definition: %w", err)
+				}
+				// ServiceArn is used as a label of the diff
+				remoteSv.ServiceArn = aws.String(other.config.ServiceDefinitionPath)
+			}
+		} else {
+			d.Log("[DEBUG] diff service compare with %s", d.config.Service)
+			remoteSv, err = d.DescribeService(ctx)
+			if err != nil {
+				if errors.As(err, &errNotFound) {
+					d.Log("[INFO] service not found, will create a new service")
+				} else {
+					return fmt.Errorf("failed to describe service: %w", err)
+				}
+			}
+			if remoteSv != nil {
+				remoteTaskDefArn = *remoteSv.TaskDefinition
 			}
 		}
 		if _, err := diffServices(ctx, newSv, remoteSv, d.config.ServiceDefinitionPath, &opt); err != nil {
 			return err
 		}
-		if remoteSv != nil {
-			remoteTaskDefArn = *remoteSv.TaskDefinition
-		}
 	}
 
 	// task definition
@@ -68,33 +128,102 @@ func (d *App) Diff(ctx context.Context, opt DiffOption) error {
 	if err != nil {
 		return err
 	}
-	if remoteTaskDefArn == "" {
-		arn, err := d.findLatestTaskDefinitionArn(ctx, *newTd.Family)
-		if err != nil {
-			if errors.As(err, &errNotFound) {
-				d.Log("[INFO] task definition not found, will register a new task definition")
-			} else {
-				return err
-			}
+	if err := d.checkTagPolicy("task definition", newTd.Tags); err != nil && opt.ToRevision == 0 {
+		d.Log("[WARNING] %s", err)
+	}
+	family := aws.ToString(newTd.Family)
+	localTdPath := d.config.TaskDefinitionPath
+	if opt.ToRevision > 0 {
+		localTdPath = fmt.Sprintf("%s:%d", family, opt.ToRevision)
+		if newTd, err = d.DescribeTaskDefinition(ctx, localTdPath); err != nil {
+			return err
 		}
-		remoteTaskDefArn = arn
 	}
+
 	var remoteTd *TaskDefinitionInput
-	if remoteTaskDefArn != "" {
+	if other != nil {
+		remoteTaskDefArn = other.config.TaskDefinitionPath
 		d.Log("[DEBUG] diff task definition compare with %s", remoteTaskDefArn)
-		remoteTd, err = d.DescribeTaskDefinition(ctx, remoteTaskDefArn)
-		if err != nil {
+		if remoteTd, err = other.LoadTaskDefinition(remoteTaskDefArn); err != nil {
 			return err
 		}
+	} else {
+		if opt.Revision > 0 {
+			remoteTaskDefArn = fmt.Sprintf("%s:%d", family, opt.Revision)
+		} else if remoteTaskDefArn == "" {
+			arn, err := d.findLatestTaskDefinitionArn(ctx, family)
+			if err != nil {
+				if errors.As(err, &errNotFound) {
+					d.Log("[INFO] task definition not found, will register a new task definition")
+				} else {
+					return err
+				}
+			}
+			remoteTaskDefArn = arn
+		}
+		if remoteTaskDefArn != "" {
+			d.Log("[DEBUG] diff task definition compare with %s", remoteTaskDefArn)
+			if remoteTd, err = d.DescribeTaskDefinition(ctx, remoteTaskDefArn); err != nil {
+				return err
+			}
+		}
 	}
 
-	if _, err := diffTaskDefs(ctx, newTd, remoteTd, d.config.TaskDefinitionPath, remoteTaskDefArn, &opt); err != nil {
+	if _, err := diffTaskDefs(ctx, newTd, remoteTd, localTdPath, remoteTaskDefArn, &opt); err != nil {
 		return err
 	}
 
+	// deployment group of CodeDeploy only when the definition is defined
+	if cd := d.config.CodeDeploy; cd != nil && cd.DeploymentGroupDefinitionPath != "" && opt.ToRevision == 0 && opt.Revision == 0 {
+		if err := d.diffDeploymentGroup(ctx, other, &opt); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case opt.isJSON():
+		return outputDiffChanges(opt.w, opt.changes)
+	case opt.isMarkdown():
+		return outputDiffMarkdown(opt.w, opt.sections)
+	}
 	return nil
 }
 
+func (d *App) diffDeploymentGroup(ctx context.Context, other *App, opt *DiffOption) error {
+	localPath := d.config.CodeDeploy.DeploymentGroupDefinitionPath
+	newDg, err := d.LoadDeploymentGroupDefinition(localPath)
+	if err != nil {
+		return err
+	}
+	var remoteDg *DeploymentGroupDefinition
+	var remoteName string
+	if other != nil {
+		ocd := other.config.CodeDeploy
+		if ocd == nil || ocd.DeploymentGroupDefinitionPath == "" {
+			return nil
+		}
+		remoteName = ocd.DeploymentGroupDefinitionPath
+		d.Log("[DEBUG] diff deployment group compare with %s", remoteName)
+		if remoteDg, err = other.LoadDeploymentGroupDefinition(remoteName); err != nil {
+			return err
+		}
+	} else {
+		remoteName = d.deploymentGroupLabel()
+		d.Log("[DEBUG] diff deployment group compare with %s", remoteName)
+		info, err := d.describeDeploymentGroup(ctx)
+		if err != nil {
+			if !errors.As(err, &errNotFound) {
+				return err
+			}
+			d.Log("[INFO] %s, will create a new deployment group", err)
+		} else {
+			remoteDg = newDeploymentGroupDefinition(info)
+		}
+	}
+	_, err = diffDeploymentGroups(ctx, newDg, remoteDg, localPath, remoteName, opt)
+	return err
+}
+
 type ServiceForDiff struct {
 	*ecs.UpdateServiceInput
 	Tags []types.Tag
@@ -129,16 +258,25 @@ func diffServices(ctx context.Context, local, remote *Service, localPath string,
 	}
 
 	switch {
+	case opt.isJSON():
+		changes, err := structuredDiff("service", remoteSvBytes, newSvBytes)
+		if err != nil {
+			return false, err
+		}
+		opt.changes = append(opt.changes, changes...)
+		return len(changes) > 0, nil
+	case opt.isMarkdown():
+		return true, opt.addMarkdownSection("service", remoteArn, localPath, remoteSv, newSv)
 	case opt.External != "":
 		return true, diffExternal(ctx, opt.External, "service", remoteSv, newSv, opt)
 	case opt.Unified:
 		edits := myers.ComputeEdits(span.URIFromPath(remoteArn), remoteSv, newSv)
 		ds := fmt.Sprint(gotextdiff.ToUnified(remoteArn, localPath, remoteSv, edits))
-		fmt.Fprint(opt.w, coloredDiff(ds))
+		fmt.Fprint(opt.w, opt.coloredDiff(ds))
 		return true, nil
 	default:
 		ds := diff.Diff(remoteSv, newSv)
-		fmt.Fprint(opt.w, coloredDiff(fmt.Sprintf("--- %s\n+++ %s\n%s", remoteArn, localPath, ds)))
+		fmt.Fprint(opt.w, opt.coloredDiff(fmt.Sprintf("--- %s\n+++ %s\n%s", remoteArn, localPath, ds)))
 		return true, nil
 	}
 }
@@ -156,6 +294,12 @@ func diffTaskDefs(ctx context.Context, local, remote *TaskDefinitionInput, local
 	if err != nil {
 		return false, fmt.Errorf("failed to marshal remote task definition: %w", err)
 	}
+	if newTdBytes, err = opt.masker.maskJSON(newTdBytes); err != nil {
+		return false, err
+	}
+	if remoteTdBytes, err = opt.masker.maskJSON(remoteTdBytes); err != nil {
+		return false, err
+	}
 
 	remoteTd := toDiffString(remoteTdBytes)
 	newTd := toDiffString(newTdBytes)
@@ -164,16 +308,65 @@ func diffTaskDefs(ctx context.Context, local, remote *TaskDefinitionInput, local
 	}
 
 	switch {
+	case opt.isJSON():
+		changes, err := structuredDiff("taskdef", remoteTdBytes, newTdBytes)
+		if err != nil {
+			return false, err
+		}
+		opt.changes = append(opt.changes, changes...)
+		return len(changes) > 0, nil
+	case opt.isMarkdown():
+		return true, opt.addMarkdownSection("taskdef", remoteArn, localPath, remoteTd, newTd)
 	case opt.External != "":
 		return true, diffExternal(ctx, opt.External, "taskdef", remoteTd, newTd, opt)
 	case opt.Unified:
 		edits := myers.ComputeEdits(span.URIFromPath(remoteArn), remoteTd, newTd)
 		ds := fmt.Sprint(gotextdiff.ToUnified(remoteArn, localPath, remoteTd, edits))
-		fmt.Fprint(opt.w, coloredDiff(ds))
+		fmt.Fprint(opt.w, opt.coloredDiff(ds))
 		return true, nil
 	default:
 		ds := diff.Diff(remoteTd, newTd)
-		fmt.Fprint(opt.w, coloredDiff(fmt.Sprintf("--- %s\n+++ %s\n%s", remoteArn, localPath, ds)))
+		fmt.Fprint(opt.w, opt.coloredDiff(fmt.Sprintf("--- %s\n+++ %s\n%s", remoteArn, localPath, ds)))
+		return true, nil
+	}
+}
+
+func diffDeploymentGroups(ctx context.Context, local, remote *DeploymentGroupDefinition, localPath, remoteName string, opt *DiffOption) (bool, error) {
+	newDgBytes, err := MarshalJSONForAPI(local)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal new deployment group definition: %w", err)
+	}
+	remoteDgBytes, err := MarshalJSONForAPI(remote)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal remote deployment group definition: %w", err)
+	}
+
+	remoteDg := toDiffString(remoteDgBytes)
+	newDg := toDiffString(newDgBytes)
+	if remoteDg == newDg {
+		return false, nil
+	}
+
+	switch {
+	case opt.isJSON():
+		changes, err := structuredDiff("deploymentgroup", remoteDgBytes, newDgBytes)
+		if err != nil {
+			return false, err
+		}
+		opt.changes = append(opt.changes, changes...)
+		return len(changes) > 0, nil
+	case opt.isMarkdown():
+		return true, opt.addMarkdownSection("deploymentgroup", remoteName, localPath, remoteDg, newDg)
+	case opt.External != "":
+		return true, diffExternal(ctx, opt.External, "deploymentgroup", remoteDg, newDg, opt)
+	case opt.Unified:
+		edits := myers.ComputeEdits(span.URIFromPath(remoteName), remoteDg, newDg)
+		ds := fmt.Sprint(gotextdiff.ToUnified(remoteName, localPath, remoteDg, edits))
+		fmt.Fprint(opt.w, opt.coloredDiff(ds))
+		return true, nil
+	default:
+		ds := diff.Diff(remoteDg, newDg)
+		fmt.Fprint(opt.w, opt.coloredDiff(fmt.Sprintf("--- %s\n+++ %s\n%s", remoteName, localPath, ds)))
 		return true, nil
 	}
 }
@@ -222,8 +415,8 @@ func diffExternal(ctx context.Context, diffCmd string, target, remote, local str
 	return nil
 }
 
-func coloredDiff(src string) string {
-	if color.NoColor {
+func (opt *DiffOption) coloredDiff(src string) string {
+	if color.NoColor || opt.noColor {
 		// disable color
 		return src
 	}
@@ -322,8 +515,16 @@ func ServiceDefinitionForDiff(sv *Service) *ServiceForDiff {
 			})
 		}
 	}
+	in := svToUpdateServiceInput(sv)
+	if dc := in.DeploymentConfiguration; dc != nil && dc.Strategy == "" && (sv.DeploymentController == nil || sv.DeploymentController.Type == types.DeploymentControllerTypeEcs) {
+		// ROLLING is the default deployment strategy.
+		// set it to a copy, because sv may be used to update the service.
+		c := *dc
+		c.Strategy = types.DeploymentStrategyRolling
+		in.DeploymentConfiguration = &c
+	}
 	return &ServiceForDiff{
-		UpdateServiceInput: svToUpdateServiceInput(sv),
+		UpdateServiceInput: in,
 		Tags:               sv.Tags,
 	}
 }
@@ -0,0 +1,234 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+const (
+	DiffChangeAdd    = "add"
+	DiffChangeRemove = "remove"
+	DiffChangeUpdate = "update"
+)
+
+// DiffChange represents a change of a field between remote and local definitions.
+type DiffChange struct {
+	Resource string      `json:"resource"`
+	Path     string      `json:"path"`
+	Type     string      `json:"type"`
+	Old      interface{} `json:"old,omitempty"`
+	New      interface{} `json:"new,omitempty"`
+}
+
+// diffKeyedObjects are arrays of objects matched by the key field, e.g. containerDefinitions[name=app].
+var diffKeyedObjects = map[string]string{
+	"containerDefinitions": "name",
+	"volumes":              "name",
+	"ulimits":              "name",
+	"systemControls":       "namespace",
+}
+
+// diffKeyValues are arrays of key-value pairs matched by the key field, e.g. environment[FOO].
+var diffKeyValues = map[string][2]string{
+	"environment": {"name", "value"},
+	"secrets":     {"name", "valueFrom"},
+	"properties":  {"name", "value"},
+	"tags":        {"key", "value"},
+}
+
+// diffNumericFields are compared numerically even if they are strings.
+var diffNumericFields = map[string]bool{
+	"cpu":    true,
+	"memory": true,
+}
+
+// structuredDiff returns changes between remote and local values marshaled for API.
+func structuredDiff(resource string, remote, local []byte) ([]DiffChange, error) {
+	var r, l interface{}
+	if s := toDiffString(remote); s != "" {
+		if err := json.Unmarshal(remote, &r); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal remote %s: %w", resource, err)
+		}
+	}
+	if s := toDiffString(local); s != "" {
+		if err := json.Unmarshal(local, &l); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal local %s: %w", resource, err)
+		}
+	}
+	d := &structuredDiffer{resource: resource}
+	d.diff("", "", r, l)
+	return d.changes, nil
+}
+
+type structuredDiffer struct {
+	resource string
+	changes  []DiffChange
+}
+
+func (d *structuredDiffer) add(path, typ string, old, new interface{}) {
+	d.changes = append(d.changes, DiffChange{
+		Resource: d.resource,
+		Path:     path,
+		Type:     typ,
+		Old:      old,
+		New:      new,
+	})
+}
+
+func (d *structuredDiffer) diff(path, key string, old, new interface{}) {
+	switch {
+	case old == nil && new == nil:
+		return
+	case old == nil:
+		d.add(path, DiffChangeAdd, nil, new)
+		return
+	case new == nil:
+		d.add(path, DiffChangeRemove, old, nil)
+		return
+	}
+
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if n, ok := new.(map[string]interface{}); ok {
+			d.diffMap(path, o, n)
+			return
+		}
+	case []interface{}:
+		if n, ok := new.([]interface{}); ok {
+			d.diffArray(path, key, o, n)
+			return
+		}
+	}
+	if diffNumericFields[key] && numericEqual(old, new) {
+		return
+	}
+	if !reflect.DeepEqual(old, new) {
+		d.add(path, DiffChangeUpdate, old, new)
+	}
+}
+
+func (d *structuredDiffer) diffMap(path string, old, new map[string]interface{}) {
+	keys := make([]string, 0, len(old)+len(new))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+		d.diff(p, k, old[k], new[k])
+	}
+}
+
+func (d *structuredDiffer) diffArray(path, key string, old, new []interface{}) {
+	if field, ok := diffKeyedObjects[key]; ok {
+		d.diffKeyed(path, old, new, func(v interface{}) (string, interface{}, bool) {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return "", nil, false
+			}
+			k, ok := m[field].(string)
+			return field + "=" + k, v, ok
+		})
+		return
+	}
+	if kv, ok := diffKeyValues[key]; ok {
+		d.diffKeyed(path, old, new, func(v interface{}) (string, interface{}, bool) {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return "", nil, false
+			}
+			k, ok := m[kv[0]].(string)
+			return k, m[kv[1]], ok
+		})
+		return
+	}
+	for i := 0; i < len(old) || i < len(new); i++ {
+		var o, n interface{}
+		if i < len(old) {
+			o = old[i]
+		}
+		if i < len(new) {
+			n = new[i]
+		}
+		d.diff(fmt.Sprintf("%s[%d]", path, i), "", o, n)
+	}
+}
+
+// diffKeyed matches elements of arrays by keys. When any element has no key, arrays are compared by index.
+func (d *structuredDiffer) diffKeyed(path string, old, new []interface{}, keyOf func(interface{}) (string, interface{}, bool)) {
+	var keys []string
+	olds, news := make(map[string]interface{}), make(map[string]interface{})
+	for _, set := range []struct {
+		list []interface{}
+		m    map[string]interface{}
+	}{{old, olds}, {new, news}} {
+		for _, v := range set.list {
+			k, value, ok := keyOf(v)
+			if !ok {
+				d.diffArray(path, "", old, new)
+				return
+			}
+			if _, exists := olds[k]; !exists {
+				if _, exists := news[k]; !exists {
+					keys = append(keys, k)
+				}
+			}
+			set.m[k] = value
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		d.diff(fmt.Sprintf("%s[%s]", path, k), "", olds[k], news[k])
+	}
+}
+
+func numericEqual(a, b interface{}) bool {
+	fa, ok := toFloat(a)
+	if !ok {
+		return false
+	}
+	fb, ok := toFloat(b)
+	return ok && fa == fb
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		if p := toNumberCPU(v); p != nil {
+			v = *p
+		}
+		if p := toNumberMemory(v); p != nil {
+			v = *p
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func outputDiffChanges(w io.Writer, changes []DiffChange) error {
+	if changes == nil {
+		changes = []DiffChange{}
+	}
+	b, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal diff changes: %w", err)
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,83 @@
+package ecspresso
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+)
+
+type diffSection struct {
+	resource string
+	remote   string
+	local    string
+	diff     string
+	changes  []DiffChange
+}
+
+func (opt *DiffOption) isMarkdown() bool {
+	return opt.Format == "markdown"
+}
+
+// addMarkdownSection adds a section of the resource to the markdown report.
+func (opt *DiffOption) addMarkdownSection(resource, remoteArn, localPath, remote, local string) error {
+	changes, err := structuredDiff(resource, []byte(remote), []byte(local))
+	if err != nil {
+		return err
+	}
+	edits := myers.ComputeEdits(span.URIFromPath(remoteArn), remote, local)
+	opt.sections = append(opt.sections, diffSection{
+		resource: resource,
+		remote:   remoteArn,
+		local:    localPath,
+		diff:     fmt.Sprint(gotextdiff.ToUnified(remoteArn, localPath, remote, edits)),
+		changes:  changes,
+	})
+	return nil
+}
+
+func outputDiffMarkdown(w io.Writer, sections []diffSection) error {
+	var b strings.Builder
+	b.WriteString("### ecspresso diff\n\n")
+	if len(sections) == 0 {
+		b.WriteString("No changes.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	b.WriteString("| Resource | Field | Change |\n")
+	b.WriteString("|----------|-------|--------|\n")
+	for _, s := range sections {
+		for _, c := range s.changes {
+			path := c.Path
+			if path == "" {
+				path = "(all)"
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %s |\n", s.resource, escapeMarkdownTable(path), c.Type)
+		}
+	}
+	b.WriteString("\n")
+
+	for _, s := range sections {
+		remote := s.remote
+		if remote == "" {
+			remote = "(new)"
+		}
+		fmt.Fprintf(&b, "<details>\n<summary>%s: %s → %s (%d changes)</summary>\n\n", s.resource, remote, s.local, len(s.changes))
+		b.WriteString("```diff\n")
+		b.WriteString(s.diff)
+		if !strings.HasSuffix(s.diff, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("```\n\n</details>\n\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeMarkdownTable(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
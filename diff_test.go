@@ -3,10 +3,15 @@ package ecspresso_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/fatih/color"
 	"github.com/google/go-cmp/cmp"
@@ -360,3 +365,220 @@ func TestDiffTaskDefs(t *testing.T) {
 		}
 	})
 }
+
+func TestDiffTaskDefsJSON(t *testing.T) {
+	ctx := context.Background()
+	remote := &ecspresso.TaskDefinitionInput{
+		Cpu:    aws.String("256"),
+		Memory: aws.String("1024"),
+		ContainerDefinitions: []types.ContainerDefinition{
+			{
+				Name:  aws.String("web"),
+				Image: aws.String("nginx:1.25"),
+			},
+			{
+				Name:  aws.String("app"),
+				Image: aws.String("debian:buster"),
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("TZ"), Value: aws.String("UTC")},
+					{Name: aws.String("FOO"), Value: aws.String("foo")},
+				},
+				Secrets: []types.Secret{
+					{Name: aws.String("PASSWORD"), ValueFrom: aws.String("/app/password")},
+				},
+			},
+		},
+	}
+	local := &ecspresso.TaskDefinitionInput{
+		Cpu:    aws.String("0.25 vCPU"),
+		Memory: aws.String("1 GB"),
+		ContainerDefinitions: []types.ContainerDefinition{
+			{
+				Name:  aws.String("app"),
+				Image: aws.String("debian:buster"),
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("BAR"), Value: aws.String("bar")},
+					{Name: aws.String("FOO"), Value: aws.String("FOO")},
+					{Name: aws.String("TZ"), Value: aws.String("UTC")},
+				},
+			},
+			{
+				Name:  aws.String("web"),
+				Image: aws.String("nginx:1.27"),
+			},
+		},
+	}
+	opt := &ecspresso.DiffOption{Output: "json"}
+	opt.SetWriter(new(bytes.Buffer))
+	differ, err := ecspresso.DiffTaskDefs(ctx, local, remote, "file", "remote", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !differ {
+		t.Error("expected differ")
+	}
+	expected := []ecspresso.DiffChange{
+		{Resource: "taskdef", Path: "containerDefinitions[name=app].environment[BAR]", Type: "add", New: "bar"},
+		{Resource: "taskdef", Path: "containerDefinitions[name=app].environment[FOO]", Type: "update", Old: "foo", New: "FOO"},
+		{Resource: "taskdef", Path: "containerDefinitions[name=app].secrets", Type: "remove", Old: []interface{}{
+			map[string]interface{}{"name": "PASSWORD", "valueFrom": "/app/password"},
+		}},
+		{Resource: "taskdef", Path: "containerDefinitions[name=web].image", Type: "update", Old: "nginx:1.25", New: "nginx:1.27"},
+	}
+	if diff := cmp.Diff(expected, opt.Changes()); diff != "" {
+		t.Errorf("unexpected changes: %s", diff)
+	}
+}
+
+func TestDiffServicesMarkdown(t *testing.T) {
+	ctx := context.Background()
+	noColor := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = noColor }()
+
+	opt := &ecspresso.DiffOption{Unified: true, Format: "markdown"}
+	b := new(bytes.Buffer)
+	opt.SetWriter(b)
+	if _, err := ecspresso.DiffServices(
+		ctx,
+		testServiceDefinitionHasDesiredCount,
+		testServiceDefinitionNoDesiredCount,
+		"ecs-service-def.json", opt,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("unexpected output before rendering: %s", b.String())
+	}
+	if err := opt.OutputMarkdown(b); err != nil {
+		t.Fatal(err)
+	}
+	md := b.String()
+	for _, s := range []string{
+		"### ecspresso diff\n",
+		"| service | `desiredCount` | add |\n",
+		"<details>\n<summary>service: (new) → ecs-service-def.json (1 changes)</summary>\n",
+		"```diff\n",
+		"+  \"desiredCount\": 2,\n",
+		"</details>\n",
+	} {
+		if !strings.Contains(md, s) {
+			t.Errorf("markdown does not contain %q:\n%s", s, md)
+		}
+	}
+	if strings.Contains(md, "\x1b[") {
+		t.Errorf("markdown must not be colored: %s", md)
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	ctx := context.Background()
+	staging, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/diff/staging.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/diff/prod.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := ecspresso.DiffOption{Output: "json"}
+	b := new(bytes.Buffer)
+	opt.SetWriter(b)
+	if err := prod.DiffConfig(ctx, staging, opt); err != nil {
+		t.Fatal(err)
+	}
+	var changes []ecspresso.DiffChange
+	if err := json.Unmarshal(b.Bytes(), &changes); err != nil {
+		t.Fatal(err)
+	}
+	expected := []ecspresso.DiffChange{
+		{Resource: "taskdef", Path: "containerDefinitions[name=app].environment[ENV]", Type: "update", Old: "staging", New: "prod"},
+		{Resource: "taskdef", Path: "family", Type: "update", Old: "app-staging", New: "app-prod"},
+	}
+	if diff := cmp.Diff(expected, changes); diff != "" {
+		t.Errorf("unexpected changes: %s", diff)
+	}
+
+	opt.Revision = 1
+	if err := prod.DiffConfig(ctx, staging, opt); err == nil {
+		t.Error("expected conflict error")
+	}
+}
+
+func TestDiffOutputFileNoColor(t *testing.T) {
+	ctx := context.Background()
+	staging, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/diff/staging.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/diff/prod.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// --color is enabled by default
+	noColor := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = noColor })
+
+	path := filepath.Join(t.TempDir(), "diff.txt")
+	if err := prod.DiffConfig(ctx, staging, ecspresso.DiffOption{Unified: true, OutputFile: path}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `+  "family": "app-prod",`) {
+		t.Errorf("unexpected diff: %s", b)
+	}
+	if strings.Contains(string(b), "\x1b[") {
+		t.Errorf("diff written to the file must not be colored: %q", b)
+	}
+}
+
+func TestDeployDoesNotSetDefaultStrategy(t *testing.T) {
+	defer ecspresso.SetDelayForServiceChanged(0)()
+	app, m := newSDKMockApp(t, "tests/alarm/ecspresso.yml", map[string]any{
+		"DescribeServices": &ecs.DescribeServicesOutput{
+			Services: []types.Service{
+				{
+					ServiceName:          aws.String("test"),
+					ClusterArn:           aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/default"),
+					Status:               aws.String("ACTIVE"),
+					TaskDefinition:       aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:1"),
+					DeploymentController: &types.DeploymentController{Type: types.DeploymentControllerTypeEcs},
+					DeploymentConfiguration: &types.DeploymentConfiguration{
+						Strategy: types.DeploymentStrategyRolling,
+					},
+				},
+			},
+		},
+		"DescribeScalableTargets": &applicationautoscaling.DescribeScalableTargetsOutput{},
+		"UpdateService": &ecs.UpdateServiceOutput{
+			Service: &types.Service{ServiceArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:service/default/test")},
+		},
+		"TagResource":            &ecs.TagResourceOutput{},
+		"DescribeTaskDefinition": &ecs.DescribeTaskDefinitionOutput{TaskDefinition: &types.TaskDefinition{Family: aws.String("test")}},
+		"DescribeClusters":       &ecs.DescribeClustersOutput{Clusters: []types.Cluster{{ClusterName: aws.String("default")}}},
+	})
+	if err := app.Deploy(context.Background(), ecspresso.DeployOption{
+		SkipTaskDefinition: true,
+		UpdateService:      true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var in *ecs.UpdateServiceInput
+	for _, c := range m.ops() {
+		if c.op == "UpdateService" {
+			in = c.in.(*ecs.UpdateServiceInput) // updating the service attributes
+			break
+		}
+	}
+	if in == nil {
+		t.Fatal("UpdateService is not called")
+	}
+	// the default strategy is filled only for comparison
+	if dc := in.DeploymentConfiguration; dc == nil || dc.Strategy != "" {
+		t.Errorf("unexpected deployment configuration %#v", dc)
+	}
+}
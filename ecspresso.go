@@ -86,6 +86,13 @@ func (d *App) newServiceFromTypes(ctx context.Context, in types.Service) (*Servi
 		// CodeDeploy does not support ServiceConnectConfiguration and VolumeConfigurations
 		return &sv, nil
 	}
+	if sv.isExternal() {
+		// the task definition of the service is defined by the primary task set
+		if ts, ok := sv.primaryTaskSet(); ok && sv.TaskDefinition == nil {
+			sv.TaskDefinition = ts.TaskDefinition
+		}
+		return &sv, nil
+	}
 	if len(dps) == 0 {
 		d.Log("[WARNING] no primary deployment")
 		return &sv, nil
@@ -521,6 +528,9 @@ func (d *App) LoadTaskDefinition(path string) (*TaskDefinitionInput, error) {
 	if len(td.Tags) == 0 {
 		td.Tags = nil
 	}
+	if err := d.config.Tags.Apply(&td); err != nil {
+		return nil, fmt.Errorf("failed to apply default tags: %w", err)
+	}
 	if err := d.config.Ignore.Apply(&td); err != nil {
 		return nil, fmt.Errorf("failed to apply ignore: %w", err)
 	}
@@ -572,6 +582,9 @@ func (d *App) LoadServiceDefinition(path string) (*Service, error) {
 		d.Log("[DEBUG] Loaded DesiredCount: %d", *sv.DesiredCount)
 	}
 
+	if err := d.config.Tags.Apply(&sv); err != nil {
+		return nil, fmt.Errorf("failed to apply default tags: %w", err)
+	}
 	if err := d.config.Ignore.Apply(&sv); err != nil {
 		return nil, fmt.Errorf("failed to apply ignore: %w", err)
 	}
@@ -18,6 +18,12 @@ func (e ErrConflictOptions) Error() string {
 	return string(e)
 }
 
+type ErrLocked string
+
+func (e ErrLocked) Error() string {
+	return string(e)
+}
+
 var (
 	errNotFound   = ErrNotFound("not found")
 	errSkipVerify = ErrSkipVerify("skip verify")
@@ -4,30 +4,34 @@ import (
 	"context"
 	"io"
 	"log"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 )
 
 var (
-	SortTaskDefinition = sortTaskDefinition
-	ToNumberCPU        = toNumberCPU
-	ToNumberMemory     = toNumberMemory
-	CalcDesiredCount   = calcDesiredCount
-	ParseTags          = parseTags
-	ExtractRoleName    = extractRoleName
-	IsLongArnFormat    = isLongArnFormat
-	ECRImageURLRegex   = ecrImageURLRegex
-	NewLogger          = newLogger
-	NewLogFilter       = newLogFilter
-	NewConfigLoader    = newConfigLoader
-	NewVerifier        = newVerifier
-	ArnToName          = arnToName
-	InitVerifyState    = initVerifyState
-	VerifyResource     = verifyResource
-	Map2str            = map2str
-	DiffServices       = diffServices
-	DiffTaskDefs       = diffTaskDefs
+	SortTaskDefinition         = sortTaskDefinition
+	ToNumberCPU                = toNumberCPU
+	ToNumberMemory             = toNumberMemory
+	CalcDesiredCount           = calcDesiredCount
+	ParseTags                  = parseTags
+	ExtractRoleName            = extractRoleName
+	IsLongArnFormat            = isLongArnFormat
+	ECRImageURLRegex           = ecrImageURLRegex
+	NewLogger                  = newLogger
+	NewLogFilter               = newLogFilter
+	NewConfigLoader            = newConfigLoader
+	NewVerifier                = newVerifier
+	ArnToName                  = arnToName
+	InitVerifyState            = initVerifyState
+	VerifyResource             = verifyResource
+	Map2str                    = map2str
+	DiffServices               = diffServices
+	DiffTaskDefs               = diffTaskDefs
+	IsPublicRouteTable         = isPublicRouteTable
+	SecurityGroupsAllowIngress = securityGroupsAllowIngress
 )
 
 type ModifyAutoScalingParams = modifyAutoScalingParams
@@ -56,6 +60,192 @@ func (opt *DiffOption) SetWriter(w io.Writer) {
 	opt.w = w
 }
 
+func SetVerifyParallel(n int) {
+	verifyState.sem = make(chan struct{}, n-1)
+}
+
+func VerifyResources(ctx context.Context, names []string, fn func(context.Context, string) error) error {
+	targets := make([]verifyTarget, 0, len(names))
+	for _, name := range names {
+		name := name
+		targets = append(targets, verifyTarget{
+			name: name,
+			fn: func(ctx context.Context) error {
+				return fn(ctx, name)
+			},
+		})
+	}
+	return verifyResources(ctx, targets).orNil()
+}
+
+func NewVerifyReport() *VerifyReport {
+	return newVerifyReport(verifyState.root)
+}
+
 func (i *ConfigIgnore) FilterTags(tags []types.Tag) []types.Tag {
 	return i.filterTags(tags)
 }
+
+// ExecutionRolePermissions returns actions grouped by resources which the execution role requires.
+func ExecutionRolePermissions(td *TaskDefinitionInput, roleArn, region string) (map[string][]string, error) {
+	ps, err := executionRolePermissions(td, roleArn, region)
+	if err != nil {
+		return nil, err
+	}
+	_, m := ps.byResource()
+	return m, nil
+}
+
+func TaskRolePermissions(c *ConfigVerify) map[string][]string {
+	_, m := taskRolePermissions(c).byResource()
+	return m
+}
+
+var (
+	CheckCapacityProviders   = checkCapacityProviders
+	UsesEC2                  = usesEC2
+	TaskResourceRequirements = taskResourceRequirements
+	InstancesFitTask         = instancesFitTask
+)
+
+func (opt *DiffOption) Changes() []DiffChange {
+	return opt.changes
+}
+
+func (opt *DiffOption) OutputMarkdown(w io.Writer) error {
+	return outputDiffMarkdown(w, opt.sections)
+}
+
+func MaskJSON(c *ConfigMask, b []byte) ([]byte, error) {
+	m, err := newSecretMasker(c)
+	if err != nil {
+		return nil, err
+	}
+	return m.maskJSON(b)
+}
+
+func (opt *DiffOption) SetMask(c *ConfigMask) error {
+	m, err := newSecretMasker(c)
+	if err != nil {
+		return err
+	}
+	opt.masker = m
+	return nil
+}
+
+func RestoreInputs(path, resourceId string) ([]*applicationautoscaling.RegisterScalableTargetInput, []*applicationautoscaling.PutScalingPolicyInput, error) {
+	as, err := loadSnapshotAutoScaling(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return as.registerScalableTargetInputs(resourceId), as.putScalingPolicyInputs(resourceId), nil
+}
+
+func RewriteJsonnet(accountID, region, cluster string, src []byte) ([]byte, map[string]string) {
+	r := newJsonnetRewriter(accountID, region, cluster)
+	return r.rewrite(src), r.rewriteMap()
+}
+
+func (c *ConfigTags) Check(tags []types.Tag) error {
+	return c.check(tags)
+}
+
+func (d *App) AcquireLock(ctx context.Context, command string) (func(), error) {
+	return d.acquireLock(ctx, command)
+}
+
+func SetLockRetryInterval(d time.Duration) func() {
+	orig := lockRetryInterval
+	lockRetryInterval = d
+	return func() { lockRetryInterval = orig }
+}
+
+func WriteAuditRecordToFile(path string, r *AuditRecord) error {
+	return (&fileAuditSink{path: path}).write(context.Background(), r)
+}
+
+func ReadAuditRecordsFromFile(path, cluster, service string, limit int) ([]*AuditRecord, error) {
+	return (&fileAuditSink{path: path}).read(context.Background(), cluster, service, limit)
+}
+
+func (d *App) ReadAuditRecordsFromCloudWatchLogs(ctx context.Context, group, stream string, limit int) ([]*AuditRecord, error) {
+	return (&cwlAuditSink{client: d.cwl, group: group, stream: stream}).read(ctx, d.Cluster, d.Service, limit)
+}
+
+func (d *App) AddTaskDefinitionDiffSummary(ctx context.Context, r *AuditRecord, previous, current string) {
+	d.addTaskDefinitionDiffSummary(ctx, r, previous, current)
+}
+
+func (r *AuditRecord) AddDiffSummary(changes []DiffChange) {
+	r.addDiffSummary(changes)
+}
+
+func (d *App) Notify(ctx context.Context, n *Notification, event string) {
+	d.notify(ctx, n, event)
+}
+
+func (d *App) NotifyResult(ctx context.Context, n *Notification, err error, succeeded, failed string) {
+	d.notifyResult(ctx, n, err, succeeded, failed)
+}
+
+func (d *App) StartHooks(ctx context.Context, command string) (context.Context, *HookContext) {
+	return d.startHooks(ctx, command)
+}
+
+func (d *App) RunHooks(ctx context.Context, name string) error {
+	return d.runHooks(ctx, name)
+}
+
+func (d *App) RunFailureHooks(ctx context.Context, err error) {
+	d.runFailureHooks(ctx, err)
+}
+
+func (d *App) PreRunTask(ctx context.Context, tdArn string, opt DeployOption) error {
+	return d.preRunTask(ctx, tdArn, opt)
+}
+
+func (d *App) HTTPSmokeTest(ctx context.Context) error {
+	return d.httpSmokeTest(ctx, nil)
+}
+
+func LoadBalancerURL(host string, h *SmokeTestHTTP) string {
+	return loadBalancerURL(host, h)
+}
+
+func (d *App) WatchAlarms(ctx context.Context) error {
+	return d.watchAlarms(ctx)
+}
+
+func (d *App) AlarmWatchWait(ctx context.Context, rollbackTdArn string, doWait func(context.Context, *Service) error) error {
+	return d.alarmWatchWaitFunc(doWait, rollbackTdArn)(ctx, &Service{})
+}
+
+func SetDelayForServiceChanged(d time.Duration) func() {
+	orig := delayForServiceChanged
+	delayForServiceChanged = d
+	return func() { delayForServiceChanged = orig }
+}
+
+func (o *CodeDeployOption) Command() string {
+	return o.command
+}
+
+func (d *App) ApplyDeploymentGroup(ctx context.Context, opt DeployOption) error {
+	return d.applyDeploymentGroup(ctx, opt)
+}
+
+func (d *App) DiffDeploymentGroup(ctx context.Context, opt *DiffOption) error {
+	return d.diffDeploymentGroup(ctx, nil, opt)
+}
+
+func (d *App) RollbackTarget(ctx context.Context, sv *Service) (string, error) {
+	return d.rollbackTarget(ctx, sv)
+}
+
+func (sv *Service) IsBlueGreen() bool {
+	return sv.isBlueGreen()
+}
+
+func (c *ConfigTaskSet) ScaleSteps_() []float64 {
+	return c.scaleSteps()
+}
@@ -0,0 +1,171 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+const (
+	hookPreRegister = "pre_register"
+	hookPreDeploy   = "pre_deploy"
+	hookPostDeploy  = "post_deploy"
+	hookOnFailure   = "on_failure"
+	hookPreRollback = "pre_rollback"
+)
+
+// ConfigHooks represents local commands to be run in the lifecycle of deploy and rollback.
+type ConfigHooks struct {
+	PreRegister []*ConfigHook `yaml:"pre_register,omitempty" json:"pre_register,omitempty"`
+	PreDeploy   []*ConfigHook `yaml:"pre_deploy,omitempty" json:"pre_deploy,omitempty"`
+	PostDeploy  []*ConfigHook `yaml:"post_deploy,omitempty" json:"post_deploy,omitempty"`
+	OnFailure   []*ConfigHook `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+	PreRollback []*ConfigHook `yaml:"pre_rollback,omitempty" json:"pre_rollback,omitempty"`
+}
+
+// ConfigHook represents a command of the hook. The command is run by `sh -c`.
+type ConfigHook struct {
+	Command string            `yaml:"command" json:"command"`
+	Env     map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+}
+
+func (c *ConfigHooks) hooks(name string) []*ConfigHook {
+	if c == nil {
+		return nil
+	}
+	switch name {
+	case hookPreRegister:
+		return c.PreRegister
+	case hookPreDeploy:
+		return c.PreDeploy
+	case hookPostDeploy:
+		return c.PostDeploy
+	case hookOnFailure:
+		return c.OnFailure
+	case hookPreRollback:
+		return c.PreRollback
+	}
+	return nil
+}
+
+func (c *ConfigHooks) validate() error {
+	for _, name := range []string{hookPreRegister, hookPreDeploy, hookPostDeploy, hookOnFailure, hookPreRollback} {
+		for _, h := range c.hooks(name) {
+			if h.Command == "" {
+				return fmt.Errorf("hooks.%s: command is required", name)
+			}
+		}
+	}
+	return nil
+}
+
+// HookContext represents a deployment context passed to hook commands.
+// It is passed as environment variables and JSON on stdin.
+type HookContext struct {
+	Hook                   string `json:"hook"`
+	Command                string `json:"command"`
+	Region                 string `json:"region"`
+	Cluster                string `json:"cluster"`
+	Service                string `json:"service"`
+	TaskDefinition         string `json:"taskDefinition,omitempty"`
+	PreviousTaskDefinition string `json:"previousTaskDefinition,omitempty"`
+	Error                  string `json:"error,omitempty"`
+}
+
+func (hc *HookContext) setTaskDefinition(previous, current string) {
+	if hc == nil {
+		return
+	}
+	hc.PreviousTaskDefinition = previous
+	hc.TaskDefinition = current
+}
+
+func (hc *HookContext) environ() []string {
+	return []string{
+		"ECSPRESSO_HOOK=" + hc.Hook,
+		"ECSPRESSO_COMMAND=" + hc.Command,
+		"ECSPRESSO_REGION=" + hc.Region,
+		"ECSPRESSO_CLUSTER=" + hc.Cluster,
+		"ECSPRESSO_SERVICE=" + hc.Service,
+		"ECSPRESSO_TASK_DEFINITION=" + hc.TaskDefinition,
+		"ECSPRESSO_PREVIOUS_TASK_DEFINITION=" + hc.PreviousTaskDefinition,
+		"ECSPRESSO_ERROR=" + hc.Error,
+	}
+}
+
+type hookContextKey struct{}
+
+func hookContextFromContext(ctx context.Context) *HookContext {
+	if hc, ok := ctx.Value(hookContextKey{}).(*HookContext); ok {
+		return hc
+	}
+	return nil
+}
+
+// startHooks returns a context with a new hook context of the command.
+// The hook context is nil when hooks are not configured.
+func (d *App) startHooks(ctx context.Context, command string) (context.Context, *HookContext) {
+	if d.config.Hooks == nil {
+		return ctx, nil
+	}
+	hc := &HookContext{
+		Command: command,
+		Region:  d.config.Region,
+		Cluster: d.Cluster,
+		Service: d.Service,
+	}
+	return context.WithValue(ctx, hookContextKey{}, hc), hc
+}
+
+// runHooks runs the commands of the hook in order. It stops at the first failed command.
+func (d *App) runHooks(ctx context.Context, name string) error {
+	hc := hookContextFromContext(ctx)
+	if hc == nil {
+		return nil
+	}
+	for _, h := range d.config.Hooks.hooks(name) {
+		hc.Hook = name
+		d.Log("Running %s hook: %s", name, h.Command)
+		if err := h.run(ctx, hc); err != nil {
+			return fmt.Errorf("%s hook failed: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// runHooksWithWarning runs the commands of the hook. Failures are logged as warnings.
+func (d *App) runHooksWithWarning(ctx context.Context, name string) {
+	if err := d.runHooks(ctx, name); err != nil {
+		d.Log("[WARNING] %s", err)
+	}
+}
+
+// runFailureHooks runs on_failure hooks when err is not nil.
+func (d *App) runFailureHooks(ctx context.Context, err error) {
+	hc := hookContextFromContext(ctx)
+	if hc == nil || err == nil {
+		return
+	}
+	hc.Error = err.Error()
+	// run hooks even if ctx is canceled
+	d.runHooksWithWarning(context.WithValue(context.Background(), hookContextKey{}, hc), hookOnFailure)
+}
+
+func (h *ConfigHook) run(ctx context.Context, hc *HookContext) error {
+	b, err := json.Marshal(hc)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), hc.environ()...)
+	for k, v := range h.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	return cmd.Run()
+}
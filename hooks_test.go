@@ -0,0 +1,102 @@
+package ecspresso_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestHooks(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOOK_OUT", dir)
+	app, err := ecspresso.New(context.Background(), &ecspresso.CLIOptions{ConfigFilePath: "tests/hooks/ecspresso.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, hc := app.StartHooks(context.Background(), "deploy")
+	hc.PreviousTaskDefinition = "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:1"
+	hc.TaskDefinition = "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:2"
+
+	// a non-zero exit aborts
+	t.Setenv("ALLOW_REGISTER", "false")
+	if err := app.RunHooks(ctx, "pre_register"); err == nil || !strings.Contains(err.Error(), "pre_register hook failed") {
+		t.Errorf("pre_register hook must fail: %v", err)
+	}
+	t.Setenv("ALLOW_REGISTER", "true")
+	if err := app.RunHooks(ctx, "pre_register"); err != nil {
+		t.Errorf("pre_register hook must succeed: %v", err)
+	}
+
+	if err := app.RunHooks(ctx, "pre_deploy"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "pre_deploy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got ecspresso.HookContext
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("stdin is not a JSON: %s %s", err, string(b))
+	}
+	if got.Hook != "pre_deploy" || got.Command != "deploy" || got.Service != "test" || got.TaskDefinition != hc.TaskDefinition || got.PreviousTaskDefinition != hc.PreviousTaskDefinition {
+		t.Errorf("unexpected hook context %s", string(b))
+	}
+	b, err = os.ReadFile(filepath.Join(dir, "pre_deploy.env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.TrimSpace(string(b)); s != "pre_deploy default test "+hc.TaskDefinition+" hello" {
+		t.Errorf("unexpected env %s", s)
+	}
+
+	// hooks not configured
+	if err := app.RunHooks(ctx, "post_deploy"); err != nil {
+		t.Error(err)
+	}
+
+	app.RunFailureHooks(ctx, errors.New("service is not stable"))
+	b, err = os.ReadFile(filepath.Join(dir, "on_failure.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "service is not stable" {
+		t.Errorf("unexpected error %s", string(b))
+	}
+}
+
+func TestDeployPreDeployHookFailure(t *testing.T) {
+	app, m := newSDKMockApp(t, "tests/hooks/fail.yml", map[string]any{
+		"DescribeServices": &ecs.DescribeServicesOutput{
+			Services: []types.Service{
+				{
+					ServiceName:    ptr("test"),
+					ClusterArn:     ptr("arn:aws:ecs:ap-northeast-1:123456789012:cluster/default"),
+					Status:         ptr("ACTIVE"),
+					TaskDefinition: ptr("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:1"),
+				},
+			},
+		},
+		"DescribeScalableTargets": &applicationautoscaling.DescribeScalableTargetsOutput{},
+		"UpdateService":           &ecs.UpdateServiceOutput{Service: &types.Service{}},
+	})
+	err := app.Deploy(context.Background(), ecspresso.DeployOption{
+		SkipTaskDefinition: true,
+		UpdateService:      true,
+		Wait:               true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "pre_deploy hook failed") {
+		t.Errorf("deploy must fail by the pre_deploy hook: %v", err)
+	}
+	if n := m.count("UpdateService"); n != 0 {
+		t.Errorf("UpdateService must not be called when the pre_deploy hook failed: %d calls", n)
+	}
+}
@@ -19,15 +19,20 @@ import (
 var CreateFileMode = os.FileMode(0644)
 
 type InitOption struct {
-	Region                string `help:"AWS region" env:"AWS_REGION" default:""`
-	Cluster               string `help:"ECS cluster name" default:"default"`
-	Service               string `help:"ECS service name" required:"" xor:"FROM"`
-	TaskDefinition        string `help:"ECS task definition name:revision" required:"" xor:"FROM"`
-	TaskDefinitionPath    string `help:"path to output task definition file" default:"ecs-task-def.json"`
-	ServiceDefinitionPath string `help:"path to output service definition file" default:"ecs-service-def.json"`
-	Sort                  bool   `help:"sort elements in task definition" default:"false" negatable:""`
-	ForceOverwrite        bool   `help:"overwrite existing files" default:"false"`
-	Jsonnet               bool   `help:"output files as jsonnet format" default:"false"`
+	Region                        string `help:"AWS region" env:"AWS_REGION" default:""`
+	Cluster                       string `help:"ECS cluster name" default:"default"`
+	Service                       string `help:"ECS service name" required:"" xor:"FROM"`
+	TaskDefinition                string `help:"ECS task definition name:revision" required:"" xor:"FROM"`
+	TaskDefinitionPath            string `help:"path to output task definition file" default:"ecs-task-def.json"`
+	ServiceDefinitionPath         string `help:"path to output service definition file" default:"ecs-service-def.json"`
+	DeploymentGroupDefinitionPath string `help:"path to output CodeDeploy deployment group definition file (not exported if empty)" default:""`
+	Sort                          bool   `help:"sort elements in task definition" default:"false" negatable:""`
+	ForceOverwrite                bool   `help:"overwrite existing files" default:"false"`
+	Jsonnet                       bool   `help:"output files as jsonnet format" default:"false"`
+	Rewrite                       bool   `help:"parameterize account ID, region and cluster by ext vars in Jsonnet files (implies --jsonnet)" default:"false"`
+	RewriteMap                    string `help:"path to output rewrite-map file of ext vars for --rewrite" default:"rewrite-map.json"`
+
+	rewriter *jsonnetRewriter
 }
 
 func (opt *InitOption) NewConfig(ctx context.Context, configFilePath string) (*Config, error) {
@@ -57,6 +62,14 @@ func (d *App) Init(ctx context.Context, opt InitOption) error {
 	tdOnly := opt.TaskDefinition != ""
 
 	d.LogJSON(opt)
+	if opt.Rewrite {
+		opt.Jsonnet = true
+		r, err := d.newJsonnetRewriter(ctx)
+		if err != nil {
+			return err
+		}
+		opt.rewriter = r
+	}
 	if opt.Jsonnet {
 		if ext := filepath.Ext(conf.ServiceDefinitionPath); ext == jsonExt {
 			conf.ServiceDefinitionPath = strings.TrimSuffix(conf.ServiceDefinitionPath, ext) + jsonnetExt
@@ -86,6 +99,16 @@ func (d *App) Init(ctx context.Context, opt InitOption) error {
 	if err := d.initConfigurationFile(ctx, conf.path, opt, sv, td); err != nil {
 		return err
 	}
+	if opt.rewriter != nil {
+		b, err := json.MarshalIndent(opt.rewriter.rewriteMap(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal rewrite-map to JSON: %w", err)
+		}
+		d.Log("save the rewrite-map to %s", opt.RewriteMap)
+		if err := d.saveFile(opt.RewriteMap, append(b, '\n'), CreateFileMode, opt.ForceOverwrite); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -105,6 +128,11 @@ func (d *App) initConfigurationFile(ctx context.Context, configFilePath string,
 				ApplicationName:     *info.ApplicationName,
 				DeploymentGroupName: *info.DeploymentGroupName,
 			}
+			if opt.DeploymentGroupDefinitionPath != "" {
+				if err := d.initDeploymentGroupDefinition(ctx, opt); err != nil {
+					Log("[WARNING] failed to export CodeDeploy deployment group definition: %s", err)
+				}
+			}
 		}
 	}
 	{
@@ -115,7 +143,7 @@ func (d *App) initConfigurationFile(ctx context.Context, configFilePath string,
 			if err != nil {
 				return fmt.Errorf("unable to marshal config to JSON: %w", err)
 			}
-			out, err := formatter.Format(configFilePath, string(b), formatter.DefaultOptions())
+			out, err := formatter.Format(configFilePath, string(opt.rewriter.rewrite(b)), formatter.DefaultOptions())
 			if err != nil {
 				return fmt.Errorf("unable to format config as Jsonnet: %w", err)
 			}
@@ -166,7 +194,7 @@ func (d *App) initServiceDefinition(ctx context.Context, opt InitOption) (*Servi
 		return nil, "", fmt.Errorf("unable to marshal service definition to JSON: %w", err)
 	} else {
 		if opt.Jsonnet {
-			out, err := formatter.Format(conf.ServiceDefinitionPath, string(b), formatter.DefaultOptions())
+			out, err := formatter.Format(conf.ServiceDefinitionPath, string(opt.rewriter.rewrite(b)), formatter.DefaultOptions())
 			if err != nil {
 				return nil, "", fmt.Errorf("unable to format service definition as Jsonnet: %w", err)
 			}
@@ -193,7 +221,7 @@ func (d *App) initTaskDefinition(ctx context.Context, opt InitOption, tdArn stri
 		return nil, fmt.Errorf("unable to marshal task definition to JSON: %w", err)
 	} else {
 		if opt.Jsonnet {
-			out, err := formatter.Format(conf.TaskDefinitionPath, string(b), formatter.DefaultOptions())
+			out, err := formatter.Format(conf.TaskDefinitionPath, string(opt.rewriter.rewrite(b)), formatter.DefaultOptions())
 			if err != nil {
 				return nil, fmt.Errorf("unable to format task definition as Jsonnet: %w", err)
 			}
@@ -207,6 +235,35 @@ func (d *App) initTaskDefinition(ctx context.Context, opt InitOption, tdArn stri
 	return td, nil
 }
 
+func (d *App) initDeploymentGroupDefinition(ctx context.Context, opt InitOption) error {
+	conf := d.config
+	path := opt.DeploymentGroupDefinitionPath
+	if ext := filepath.Ext(path); opt.Jsonnet && ext == jsonExt {
+		path = strings.TrimSuffix(path, ext) + jsonnetExt
+	}
+	info, err := d.describeDeploymentGroup(ctx)
+	if err != nil {
+		return err
+	}
+	b, err := MarshalJSONForAPI(newDeploymentGroupDefinition(info))
+	if err != nil {
+		return fmt.Errorf("unable to marshal deployment group definition to JSON: %w", err)
+	}
+	if opt.Jsonnet {
+		out, err := formatter.Format(path, string(opt.rewriter.rewrite(b)), formatter.DefaultOptions())
+		if err != nil {
+			return fmt.Errorf("unable to format deployment group definition as Jsonnet: %w", err)
+		}
+		b = []byte(out)
+	}
+	d.Log("save the deployment group definition %s to %s", d.deploymentGroupLabel(), path)
+	if err := d.saveFile(path, b, CreateFileMode, opt.ForceOverwrite); err != nil {
+		return err
+	}
+	conf.CodeDeploy.DeploymentGroupDefinitionPath = path
+	return nil
+}
+
 func treatmentServiceDefinition(sv *Service) {
 	sv.ClusterArn = nil
 	sv.CreatedAt = nil
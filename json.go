@@ -9,6 +9,7 @@ import (
 	"github.com/itchyny/gojq"
 )
 
+// OutputJSONForAPI writes v as JSON for API. Secret values are not masked, so that the output can be applied as is.
 func (d *App) OutputJSONForAPI(w io.Writer, v interface{}) error {
 	b, err := MarshalJSONForAPI(v)
 	if err != nil {
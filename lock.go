@@ -0,0 +1,451 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Songmu/prompter"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+var lockRetryInterval = 5 * time.Second
+
+// ConfigLock represents a configuration of the deployment lock.
+type ConfigLock struct {
+	DynamoDB *ConfigLockDynamoDB `yaml:"dynamodb,omitempty" json:"dynamodb,omitempty"`
+	S3       *ConfigLockS3       `yaml:"s3,omitempty" json:"s3,omitempty"`
+	File     *ConfigLockFile     `yaml:"file,omitempty" json:"file,omitempty"`
+	Timeout  *Duration           `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+type ConfigLockDynamoDB struct {
+	TableName string `yaml:"table_name" json:"table_name"`
+}
+
+type ConfigLockS3 struct {
+	Bucket    string `yaml:"bucket" json:"bucket"`
+	KeyPrefix string `yaml:"key_prefix,omitempty" json:"key_prefix,omitempty"`
+}
+
+type ConfigLockFile struct {
+	Dir string `yaml:"dir" json:"dir"`
+}
+
+func (c *ConfigLock) validate() error {
+	if c == nil {
+		return nil
+	}
+	n := 0
+	if c.DynamoDB != nil {
+		if c.DynamoDB.TableName == "" {
+			return errors.New("lock.dynamodb.table_name is required")
+		}
+		n++
+	}
+	if c.S3 != nil {
+		if c.S3.Bucket == "" {
+			return errors.New("lock.s3.bucket is required")
+		}
+		n++
+	}
+	if c.File != nil {
+		if c.File.Dir == "" {
+			return errors.New("lock.file.dir is required")
+		}
+		n++
+	}
+	if n != 1 {
+		return errors.New("lock requires exactly one of dynamodb, s3 or file")
+	}
+	return nil
+}
+
+func (c *ConfigLock) timeout() time.Duration {
+	if c.Timeout == nil {
+		return 0
+	}
+	return c.Timeout.Duration
+}
+
+// LockInfo represents a holder of the deployment lock.
+type LockInfo struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key"`
+	Holder    string    `json:"holder"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+func (l *LockInfo) String() string {
+	return fmt.Sprintf("%s (command: %s, started at: %s, id: %s)", l.Holder, l.Command, l.StartedAt.Format(time.RFC3339), l.ID)
+}
+
+// locker is a backend of the deployment lock.
+type locker interface {
+	// tryLock tries to acquire the lock. It returns the current holder when the lock is held by others.
+	tryLock(ctx context.Context, info *LockInfo) (*LockInfo, error)
+	// unlock releases the lock held by id. When id is empty, the lock is released regardless of the holder.
+	unlock(ctx context.Context, key, id string) error
+	// get returns the current holder of the lock. It returns nil when the lock is not held.
+	get(ctx context.Context, key string) (*LockInfo, error)
+}
+
+func newLocker(c *ConfigLock, awsCfg aws.Config) locker {
+	switch {
+	case c.DynamoDB != nil:
+		return &dynamoDBLocker{client: dynamodb.NewFromConfig(awsCfg), table: c.DynamoDB.TableName}
+	case c.S3 != nil:
+		return &s3Locker{client: s3.NewFromConfig(awsCfg), bucket: c.S3.Bucket, prefix: c.S3.KeyPrefix}
+	default:
+		return &fileLocker{dir: c.File.Dir}
+	}
+}
+
+func newLockInfo(key, command string) *LockInfo {
+	b := make([]byte, 16)
+	rand.Read(b)
+	user := os.Getenv("USER")
+	if user == "" {
+		user = "unknown"
+	}
+	host, _ := os.Hostname()
+	return &LockInfo{
+		ID:        hex.EncodeToString(b),
+		Key:       key,
+		Holder:    fmt.Sprintf("%s@%s:%d", user, host, os.Getpid()),
+		Command:   command,
+		StartedAt: time.Now(),
+	}
+}
+
+func (d *App) lockKey() string {
+	return d.Cluster + "/" + d.Service
+}
+
+// acquireLock acquires the deployment lock of the service and returns a function to release it.
+func (d *App) acquireLock(ctx context.Context, command string) (func(), error) {
+	c := d.config.Lock
+	if c == nil {
+		return func() {}, nil
+	}
+	l := newLocker(c, d.config.awsv2Config)
+	info := newLockInfo(d.lockKey(), command)
+	deadline := time.Now().Add(c.timeout())
+	for {
+		holder, err := l.tryLock(ctx, info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire the lock %s: %w", info.Key, err)
+		}
+		if holder == nil {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			return nil, ErrLocked(fmt.Sprintf("the service %s is locked by %s. use `ecspresso unlock` to release a stale lock", info.Key, holder))
+		}
+		d.Log("[INFO] waiting for the lock %s held by %s", info.Key, holder)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+	d.Log("[INFO] acquired the lock %s", info.Key)
+	return func() {
+		// release the lock even if ctx is canceled
+		if err := l.unlock(context.Background(), info.Key, info.ID); err != nil {
+			d.Log("[WARNING] failed to release the lock %s: %s", info.Key, err)
+			return
+		}
+		d.Log("[INFO] released the lock %s", info.Key)
+	}, nil
+}
+
+type UnlockOption struct {
+	Force bool `help:"release the lock without confirmation" default:"false"`
+}
+
+func (d *App) Unlock(ctx context.Context, opt UnlockOption) error {
+	ctx, cancel := d.Start(ctx)
+	defer cancel()
+
+	c := d.config.Lock
+	if c == nil {
+		return errors.New("lock is not configured")
+	}
+	l := newLocker(c, d.config.awsv2Config)
+	key := d.lockKey()
+	holder, err := l.get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to get the lock %s: %w", key, err)
+	}
+	if holder == nil {
+		d.Log("the lock %s is not held", key)
+		return nil
+	}
+	d.Log("the lock %s is held by %s", key, holder)
+	if !opt.Force {
+		if !prompter.YN(fmt.Sprintf("Release the lock %s?", key), false) {
+			return errors.New("aborted")
+		}
+	}
+	if err := l.unlock(ctx, key, ""); err != nil {
+		return fmt.Errorf("failed to release the lock %s: %w", key, err)
+	}
+	d.Log("the lock %s is released", key)
+	return nil
+}
+
+// fileLocker is a locker using a local file. It is useful for tests and a single host.
+type fileLocker struct {
+	dir string
+}
+
+func (l *fileLocker) path(key string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(key)+".json")
+}
+
+func (l *fileLocker) tryLock(ctx context.Context, info *LockInfo) (*LockInfo, error) {
+	p := l.path(info.Key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return l.holder(ctx, info.Key)
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return nil, json.NewEncoder(f).Encode(info)
+}
+
+func (l *fileLocker) holder(ctx context.Context, key string) (*LockInfo, error) {
+	holder, err := l.get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if holder == nil {
+		// released just now
+		return &LockInfo{Key: key, Holder: "unknown"}, nil
+	}
+	return holder, nil
+}
+
+func (l *fileLocker) unlock(ctx context.Context, key, id string) error {
+	if id != "" {
+		holder, err := l.get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if holder != nil && holder.ID != id {
+			return fmt.Errorf("the lock is held by %s", holder)
+		}
+	}
+	if err := os.Remove(l.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *fileLocker) get(ctx context.Context, key string) (*LockInfo, error) {
+	b, err := os.ReadFile(l.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var info LockInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse the lock file: %w", err)
+	}
+	return &info, nil
+}
+
+// dynamoDBLocker is a locker using a DynamoDB table which has a partition key "LockID" (string).
+type dynamoDBLocker struct {
+	client *dynamodb.Client
+	table  string
+}
+
+func (l *dynamoDBLocker) tryLock(ctx context.Context, info *LockInfo) (*LockInfo, error) {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	_, err = l.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(l.table),
+		Item: map[string]ddbTypes.AttributeValue{
+			"LockID":    &ddbTypes.AttributeValueMemberS{Value: info.Key},
+			"LockOwner": &ddbTypes.AttributeValueMemberS{Value: info.ID},
+			"Info":      &ddbTypes.AttributeValueMemberS{Value: string(b)},
+		},
+		ConditionExpression:                 aws.String("attribute_not_exists(LockID)"),
+		ReturnValuesOnConditionCheckFailure: ddbTypes.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err == nil {
+		return nil, nil
+	}
+	var ccf *ddbTypes.ConditionalCheckFailedException
+	if !errors.As(err, &ccf) {
+		return nil, err
+	}
+	if holder := parseDynamoDBLockItem(ccf.Item); holder != nil {
+		return holder, nil
+	}
+	return &LockInfo{Key: info.Key, Holder: "unknown"}, nil
+}
+
+func (l *dynamoDBLocker) unlock(ctx context.Context, key, id string) error {
+	in := &dynamodb.DeleteItemInput{
+		TableName: aws.String(l.table),
+		Key: map[string]ddbTypes.AttributeValue{
+			"LockID": &ddbTypes.AttributeValueMemberS{Value: key},
+		},
+	}
+	if id != "" {
+		in.ConditionExpression = aws.String("#owner = :id")
+		in.ExpressionAttributeNames = map[string]string{"#owner": "LockOwner"}
+		in.ExpressionAttributeValues = map[string]ddbTypes.AttributeValue{
+			":id": &ddbTypes.AttributeValueMemberS{Value: id},
+		}
+	}
+	if _, err := l.client.DeleteItem(ctx, in); err != nil {
+		var ccf *ddbTypes.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return errors.New("the lock is held by another holder")
+		}
+		return err
+	}
+	return nil
+}
+
+func (l *dynamoDBLocker) get(ctx context.Context, key string) (*LockInfo, error) {
+	out, err := l.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(l.table),
+		Key: map[string]ddbTypes.AttributeValue{
+			"LockID": &ddbTypes.AttributeValueMemberS{Value: key},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Item) == 0 {
+		return nil, nil
+	}
+	if holder := parseDynamoDBLockItem(out.Item); holder != nil {
+		return holder, nil
+	}
+	return &LockInfo{Key: key, Holder: "unknown"}, nil
+}
+
+func parseDynamoDBLockItem(item map[string]ddbTypes.AttributeValue) *LockInfo {
+	v, ok := item["Info"].(*ddbTypes.AttributeValueMemberS)
+	if !ok {
+		return nil
+	}
+	var info LockInfo
+	if err := json.Unmarshal([]byte(v.Value), &info); err != nil {
+		return nil
+	}
+	return &info
+}
+
+// s3Locker is a locker using an S3 object created by a conditional write (If-None-Match).
+type s3Locker struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (l *s3Locker) objectKey(key string) string {
+	return l.prefix + key + ".json"
+}
+
+func (l *s3Locker) tryLock(ctx context.Context, info *LockInfo) (*LockInfo, error) {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	_, err = l.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(l.bucket),
+		Key:         aws.String(l.objectKey(info.Key)),
+		Body:        bytes.NewReader(b),
+		ContentType: aws.String("application/json"),
+	}, func(o *s3.Options) {
+		// create the object only if it does not exist
+		o.APIOptions = append(o.APIOptions, smithyhttp.AddHeaderValue("If-None-Match", "*"))
+	})
+	if err == nil {
+		return nil, nil
+	}
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return nil, err
+	}
+	switch ae.ErrorCode() {
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		holder, err := l.get(ctx, info.Key)
+		if err != nil {
+			return nil, err
+		}
+		if holder == nil {
+			return &LockInfo{Key: info.Key, Holder: "unknown"}, nil
+		}
+		return holder, nil
+	}
+	return nil, err
+}
+
+func (l *s3Locker) unlock(ctx context.Context, key, id string) error {
+	if id != "" {
+		holder, err := l.get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if holder != nil && holder.ID != id {
+			return fmt.Errorf("the lock is held by %s", holder)
+		}
+	}
+	_, err := l.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(l.bucket),
+		Key:    aws.String(l.objectKey(key)),
+	})
+	return err
+}
+
+func (l *s3Locker) get(ctx context.Context, key string) (*LockInfo, error) {
+	out, err := l.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(l.bucket),
+		Key:    aws.String(l.objectKey(key)),
+	})
+	if err != nil {
+		var nsk *s3Types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+	var info LockInfo
+	if err := json.NewDecoder(out.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to parse the lock object: %w", err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,74 @@
+package ecspresso_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func newLockTestApp(t *testing.T) *ecspresso.App {
+	t.Helper()
+	app, err := ecspresso.New(context.Background(), &ecspresso.CLIOptions{ConfigFilePath: "tests/lock/ecspresso.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Config().Lock.File.Dir = t.TempDir()
+	return app
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	defer ecspresso.SetLockRetryInterval(10 * time.Millisecond)()
+	app := newLockTestApp(t)
+
+	release, err := app.AcquireLock(ctx, "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// locked by the other
+	var errLocked ecspresso.ErrLocked
+	if _, err := app.AcquireLock(ctx, "rollback"); !errors.As(err, &errLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	} else if !strings.Contains(err.Error(), "command: deploy") {
+		t.Errorf("holder is not shown: %s", err)
+	}
+	if err := app.Deploy(ctx, ecspresso.DeployOption{}); !errors.As(err, &errLocked) {
+		t.Fatalf("deploy must fail with ErrLocked, got %v", err)
+	}
+
+	// wait for the lock released
+	app.Config().Lock.Timeout = &ecspresso.Duration{Duration: 5 * time.Second}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		release()
+	}()
+	release2, err := app.AcquireLock(ctx, "rollback")
+	if err != nil {
+		t.Fatalf("failed to acquire the lock after released: %s", err)
+	}
+
+	// release a stale lock
+	if err := app.Unlock(ctx, ecspresso.UnlockOption{Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	release3, err := app.AcquireLock(ctx, "deploy")
+	if err != nil {
+		t.Fatalf("failed to acquire the lock after unlock: %s", err)
+	}
+	release3()
+	release2() // the lock was already released by unlock, so this is a no-op
+}
+
+func TestLockConfigValidate(t *testing.T) {
+	app := newLockTestApp(t)
+	conf := app.Config()
+	conf.Lock.S3 = &ecspresso.ConfigLockS3{Bucket: "example"}
+	if err := conf.Restrict(context.Background()); err == nil {
+		t.Error("multiple lock backends must be an error")
+	}
+}
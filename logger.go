@@ -51,5 +51,9 @@ func (d *App) LogJSON(v interface{}) {
 		d.logger.Printf("[WARNING] failed to marshal json: %s", err)
 		return
 	}
+	if b, err = d.config.masker.maskJSON(b); err != nil {
+		d.logger.Printf("[WARNING] failed to mask json: %s", err)
+		return
+	}
 	d.logger.Println("[DEBUG] " + string(b))
 }
@@ -0,0 +1,157 @@
+package ecspresso
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ConfigMask represents configurations for masking secret values of environment variables in outputs.
+type ConfigMask struct {
+	// Keys are regular expressions matched against names of environment variables.
+	Keys []string `yaml:"keys,omitempty" json:"keys,omitempty"`
+	// Credentials masks values which look like credentials (e.g. AWS access keys, tokens).
+	Credentials bool `yaml:"credentials,omitempty" json:"credentials,omitempty"`
+}
+
+const maskedValue = "********"
+
+var credentialPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),              // AWS access key ID
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),           // GitHub token
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),         // GitHub fine-grained token
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),          // Slack token
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.`), // JWT
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),       // private key
+	regexp.MustCompile(`://[^/\s:@]+:[^/\s@]+@`),                   // URL with a password
+	regexp.MustCompile(`\b(sk|rk)_(live|test)_[A-Za-z0-9]{16,}\b`), // Stripe key
+}
+
+type secretMasker struct {
+	keys        []*regexp.Regexp
+	credentials bool
+	salt        []byte
+}
+
+func newSecretMasker(c *ConfigMask) (*secretMasker, error) {
+	if c == nil {
+		return nil, nil
+	}
+	m := &secretMasker{credentials: c.Credentials}
+	for _, k := range c.Keys {
+		re, err := regexp.Compile(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mask key pattern %s: %w", k, err)
+		}
+		m.keys = append(m.keys, re)
+	}
+	// fingerprints of masked values are comparable only in the process.
+	m.salt = make([]byte, 32)
+	if _, err := rand.Read(m.salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt for masking: %w", err)
+	}
+	return m, nil
+}
+
+// shouldMask reports whether the value of the environment variable should be masked.
+func (m *secretMasker) shouldMask(name, value string) bool {
+	for _, re := range m.keys {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	if m.credentials {
+		for _, re := range credentialPatterns {
+			if re.MatchString(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maskValue returns a masked value with a fingerprint, so that a changed value is still shown as changed in diffs.
+func (m *secretMasker) maskValue(value string) string {
+	h := hmac.New(sha256.New, m.salt)
+	h.Write([]byte(value))
+	return maskedValue + "(" + hex.EncodeToString(h.Sum(nil))[:8] + ")"
+}
+
+// maskTree masks values of environment variables in the unmarshaled JSON value in place.
+func (m *secretMasker) maskTree(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range v {
+			if strings.EqualFold(k, "environment") {
+				if list, ok := vv.([]interface{}); ok {
+					m.maskEnvironment(list)
+					continue
+				}
+			}
+			m.maskTree(vv)
+		}
+	case []interface{}:
+		for _, vv := range v {
+			m.maskTree(vv)
+		}
+	}
+}
+
+func (m *secretMasker) maskEnvironment(list []interface{}) {
+	for _, e := range list {
+		kv, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var name, valueKey string
+		for k, v := range kv {
+			switch strings.ToLower(k) {
+			case "name":
+				name, _ = v.(string)
+			case "value":
+				valueKey = k
+			}
+		}
+		if valueKey == "" {
+			continue
+		}
+		if value, ok := kv[valueKey].(string); ok && m.shouldMask(name, value) {
+			kv[valueKey] = m.maskValue(value)
+		}
+	}
+}
+
+// maskJSON masks values of environment variables in the JSON.
+// The JSON is reformatted in the same style (indented or compact) as the input.
+func (m *secretMasker) maskJSON(b []byte) ([]byte, error) {
+	if m == nil || len(bytes.TrimSpace(b)) == 0 {
+		return b, nil
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode json for masking: %w", err)
+	}
+	if v == nil {
+		return b, nil
+	}
+	m.maskTree(v)
+	if !bytes.Contains(b, []byte("\n")) {
+		return json.Marshal(v)
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode json for masking: %w", err)
+	}
+	if bytes.HasSuffix(b, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	return out, nil
+}
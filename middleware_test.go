@@ -2,13 +2,20 @@ package ecspresso_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
+	"testing"
 
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/smithy-go/middleware"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/kayac/ecspresso/v2"
 )
 
 var middlewareResults = map[string]func(string) any{
@@ -50,3 +57,101 @@ func SDKTestingMiddleware(family string) func(*middleware.Stack) error {
 		)
 	}
 }
+
+type sdkCall struct {
+	op string
+	in any
+}
+
+// sdkMock mocks AWS SDK operations by operation names, and records called operations.
+// A result is an output, an error, or a func(input any) (any, error) responding by the input.
+type sdkMock struct {
+	mu      sync.Mutex
+	results map[string]any
+	calls   []sdkCall
+}
+
+func (m *sdkMock) middleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(
+		middleware.InitializeMiddlewareFunc(
+			"test",
+			func(ctx context.Context, in middleware.InitializeInput, handler middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				op := awsmiddleware.GetOperationName(ctx)
+				m.mu.Lock()
+				m.calls = append(m.calls, sdkCall{op: op, in: in.Parameters})
+				res, ok := m.results[op]
+				m.mu.Unlock()
+				if !ok {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, errors.New("unexpected operation " + op)
+				}
+				var err error
+				switch r := res.(type) {
+				case func(any) (any, error):
+					res, err = r(in.Parameters)
+				case error:
+					res, err = nil, r
+				}
+				if err != nil {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, err
+				}
+				return middleware.InitializeOutput{Result: res}, middleware.Metadata{}, nil
+			},
+		),
+		// the operation name is available after the Initialize step begins
+		middleware.After,
+	)
+}
+
+// called returns the input of the last call of op.
+func (m *sdkMock) called(op string) (any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.calls) - 1; i >= 0; i-- {
+		if m.calls[i].op == op {
+			return m.calls[i].in, true
+		}
+	}
+	return nil, false
+}
+
+// count returns the number of calls of op.
+func (m *sdkMock) count(op string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int
+	for _, c := range m.calls {
+		if c.op == op {
+			n++
+		}
+	}
+	return n
+}
+
+// ops returns the called operations in order except for ignore.
+func (m *sdkMock) ops(ignore ...string) []sdkCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var calls []sdkCall
+	for _, c := range m.calls {
+		if !slices.Contains(ignore, c.op) {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// newSDKMockApp returns an App loaded from configPath whose AWS SDK calls are mocked by results.
+func newSDKMockApp(t *testing.T, configPath string, results map[string]any) (*ecspresso.App, *sdkMock) {
+	t.Helper()
+	m := &sdkMock{results: results}
+	ecspresso.SetAWSV2ConfigLoadOptionsFunc([]func(*config.LoadOptions) error{
+		config.WithRegion("ap-northeast-1"),
+		config.WithAPIOptions([]func(*middleware.Stack) error{m.middleware}),
+	})
+	t.Cleanup(ecspresso.ResetAWSV2ConfigLoadOptionsFunc)
+	app, err := ecspresso.New(context.Background(), &ecspresso.CLIOptions{ConfigFilePath: configPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app, m
+}
@@ -0,0 +1,263 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/samber/lo"
+)
+
+const (
+	eventDeployStarted     = "deploy_started"
+	eventDeploySucceeded   = "deploy_succeeded"
+	eventDeployFailed      = "deploy_failed"
+	eventRollbackStarted   = "rollback_started"
+	eventRollbackSucceeded = "rollback_succeeded"
+	eventRollbackFailed    = "rollback_failed"
+	eventWaitSucceeded     = "wait_succeeded"
+	eventWaitFailed        = "wait_failed"
+)
+
+var notificationEvents = []string{
+	eventDeployStarted,
+	eventDeploySucceeded,
+	eventDeployFailed,
+	eventRollbackStarted,
+	eventRollbackSucceeded,
+	eventRollbackFailed,
+	eventWaitSucceeded,
+	eventWaitFailed,
+}
+
+var notificationTimeout = 10 * time.Second
+
+const defaultNotificationTemplate = `{{ json . }}`
+
+// ConfigNotification represents a webhook to be notified of deployment events.
+type ConfigNotification struct {
+	Name         string            `yaml:"name,omitempty" json:"name,omitempty"`
+	URL          string            `yaml:"url" json:"url"`
+	Method       string            `yaml:"method,omitempty" json:"method,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Events       []string          `yaml:"events,omitempty" json:"events,omitempty"`
+	Template     string            `yaml:"template,omitempty" json:"template,omitempty"`
+	TemplateFile string            `yaml:"template_file,omitempty" json:"template_file,omitempty"`
+
+	tmpl *template.Template
+}
+
+var notificationTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"json_escape": func(s string) (string, error) {
+		b, err := json.Marshal(s)
+		if err != nil {
+			return "", err
+		}
+		return string(b[1 : len(b)-1]), nil
+	},
+	"join": strings.Join,
+}
+
+func (c *ConfigNotification) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.URL
+}
+
+func (c *ConfigNotification) setup(dir string) error {
+	if c.URL == "" {
+		return fmt.Errorf("notifications: url is required")
+	}
+	for _, ev := range c.Events {
+		if !lo.Contains(notificationEvents, ev) {
+			return fmt.Errorf("notifications %s: unknown event %s. available events are %s", c.name(), ev, strings.Join(notificationEvents, ","))
+		}
+	}
+	if c.Template != "" && c.TemplateFile != "" {
+		return fmt.Errorf("notifications %s: template and template_file are exclusive", c.name())
+	}
+	src := c.Template
+	if c.TemplateFile != "" {
+		// template_file is not rendered as a config template
+		b, err := os.ReadFile(filepath.Join(dir, c.TemplateFile))
+		if err != nil {
+			return fmt.Errorf("notifications %s: failed to read template_file: %w", c.name(), err)
+		}
+		src = string(b)
+	}
+	if src == "" {
+		src = defaultNotificationTemplate
+	}
+	tmpl, err := template.New(c.name()).Funcs(notificationTemplateFuncs).Parse(src)
+	if err != nil {
+		return fmt.Errorf("notifications %s: failed to parse template: %w", c.name(), err)
+	}
+	c.tmpl = tmpl
+	return nil
+}
+
+func (c *ConfigNotification) subscribes(event string) bool {
+	return len(c.Events) == 0 || lo.Contains(c.Events, event)
+}
+
+// Notification represents a context of a deployment event passed to templates of notifications.
+type Notification struct {
+	Event                  string    `json:"event"`
+	Region                 string    `json:"region"`
+	Cluster                string    `json:"cluster"`
+	Service                string    `json:"service"`
+	TaskDefinition         string    `json:"taskDefinition,omitempty"`
+	PreviousTaskDefinition string    `json:"previousTaskDefinition,omitempty"`
+	Images                 []string  `json:"images,omitempty"`
+	StartedAt              time.Time `json:"startedAt"`
+	Duration               string    `json:"duration,omitempty"`
+	Error                  string    `json:"error,omitempty"`
+
+	started bool
+}
+
+func (n *Notification) setTaskDefinition(previous, current string) {
+	if n == nil {
+		return
+	}
+	n.PreviousTaskDefinition = previous
+	if n.TaskDefinition != current {
+		n.Images = nil // images of the previous task definition
+	}
+	n.TaskDefinition = current
+}
+
+type notificationKey struct{}
+
+func withNotification(ctx context.Context, n *Notification) context.Context {
+	return context.WithValue(ctx, notificationKey{}, n)
+}
+
+func notificationFromContext(ctx context.Context) *Notification {
+	if n, ok := ctx.Value(notificationKey{}).(*Notification); ok {
+		return n
+	}
+	return nil
+}
+
+// recordTaskDefinition records task definitions of the deployment for audit records, notifications and hooks.
+func recordTaskDefinition(ctx context.Context, previous, current string) {
+	auditRecordFromContext(ctx).setTaskDefinition(previous, current)
+	notificationFromContext(ctx).setTaskDefinition(previous, current)
+	hookContextFromContext(ctx).setTaskDefinition(previous, current)
+}
+
+// startNotification returns a context with a new notification of the service.
+// The notification is nil when notifications are not configured.
+func (d *App) startNotification(ctx context.Context) (context.Context, *Notification) {
+	if len(d.config.Notifications) == 0 {
+		return ctx, nil
+	}
+	n := &Notification{
+		Region:    d.config.Region,
+		Cluster:   d.Cluster,
+		Service:   d.Service,
+		StartedAt: time.Now(),
+	}
+	return withNotification(ctx, n), n
+}
+
+// notifyStarted notifies the started event only once.
+// It is also called before the result so that the result always follows the started event,
+// even if the command failed before starting (e.g. failed to register the task definition).
+func (d *App) notifyStarted(ctx context.Context, event string) {
+	n := notificationFromContext(ctx)
+	if n == nil || n.started {
+		return
+	}
+	n.started = true
+	d.notify(ctx, n, event)
+}
+
+// notifyResult notifies the succeeded or failed event by err.
+func (d *App) notifyResult(ctx context.Context, n *Notification, err error, succeeded, failed string) {
+	if n == nil {
+		return
+	}
+	if err != nil {
+		n.Error = err.Error()
+		d.notify(ctx, n, failed)
+		return
+	}
+	d.notify(ctx, n, succeeded)
+}
+
+// notify sends the event to the webhooks subscribing it. Failures of notifications are logged as warnings.
+func (d *App) notify(ctx context.Context, n *Notification, event string) {
+	if n == nil {
+		return
+	}
+	n.Event = event
+	n.Duration = time.Since(n.StartedAt).Round(time.Second).String()
+	if n.Images == nil && n.TaskDefinition != "" {
+		if td, err := d.DescribeTaskDefinition(ctx, n.TaskDefinition); err != nil {
+			d.Log("[WARNING] failed to describe task definition for notifications: %s", err)
+		} else {
+			n.Images = []string{}
+			for _, c := range td.ContainerDefinitions {
+				n.Images = append(n.Images, aws.ToString(c.Image))
+			}
+		}
+	}
+	for _, c := range d.config.Notifications {
+		if !c.subscribes(event) {
+			continue
+		}
+		d.Log("[DEBUG] notify %s to %s", event, c.name())
+		if err := c.send(n); err != nil {
+			d.Log("[WARNING] failed to notify %s to %s: %s", event, c.name(), err)
+		}
+	}
+}
+
+func (c *ConfigNotification) send(n *Notification) error {
+	var body bytes.Buffer
+	if err := c.tmpl.Execute(&body, n); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	method := c.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	// notify even if the deployment is canceled
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ecspresso/"+Version)
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
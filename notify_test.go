@@ -0,0 +1,150 @@
+package ecspresso_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+type notifyRequest struct {
+	Path          string
+	Authorization string
+	Body          string
+}
+
+func newNotifyTestServer(t *testing.T) (*httptest.Server, func() []notifyRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var reqs []notifyRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		reqs = append(reqs, notifyRequest{
+			Path:          r.URL.Path,
+			Authorization: r.Header.Get("Authorization"),
+			Body:          string(b),
+		})
+		if r.URL.Path == "/failure" && strings.Contains(string(b), "unavailable") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func() []notifyRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]notifyRequest{}, reqs...)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+	ts, requests := newNotifyTestServer(t)
+	t.Setenv("NOTIFY_URL", ts.URL)
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/notify/ecspresso.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := &ecspresso.Notification{
+		Cluster:        "default",
+		Service:        "test",
+		TaskDefinition: "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:2",
+		Images:         []string{"nginx:1.25", "fluentbit:3"},
+		StartedAt:      time.Now().Add(-90 * time.Second),
+	}
+	app.Notify(ctx, n, "deploy_started")
+	app.NotifyResult(ctx, n, errors.New(`service "test" is not stable`), "deploy_succeeded", "deploy_failed")
+
+	reqs := requests()
+	if len(reqs) != 3 {
+		t.Fatalf("unexpected number of requests: %d %#v", len(reqs), reqs)
+	}
+
+	// default payload
+	for i, event := range []string{"deploy_started", "deploy_failed"} {
+		req := reqs[i]
+		if req.Path != "/all" {
+			t.Errorf("unexpected path %s", req.Path)
+		}
+		if req.Authorization != "Bearer secret" {
+			t.Errorf("unexpected authorization header %s", req.Authorization)
+		}
+		var got ecspresso.Notification
+		if err := json.Unmarshal([]byte(req.Body), &got); err != nil {
+			t.Fatalf("payload is not a JSON: %s %s", err, req.Body)
+		}
+		if got.Event != event || got.Service != "test" || got.TaskDefinition != n.TaskDefinition || len(got.Images) != 2 {
+			t.Errorf("unexpected payload %s", req.Body)
+		}
+	}
+
+	// template_file subscribes to failures only
+	req := reqs[2]
+	if req.Path != "/failure" {
+		t.Errorf("unexpected path %s", req.Path)
+	}
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(req.Body), &payload); err != nil {
+		t.Fatalf("payload is not a JSON: %s %s", err, req.Body)
+	}
+	expected := `deploy_failed default/test nginx:1.25,fluentbit:3 (1m30s): service "test" is not stable`
+	if payload.Text != expected {
+		t.Errorf("unexpected text %q expected %q", payload.Text, expected)
+	}
+
+	// failures of notifications do not stop the deployment
+	n.Error = ""
+	app.NotifyResult(ctx, n, errors.New("unavailable"), "rollback_succeeded", "rollback_failed")
+	if got := len(requests()); got != 5 {
+		t.Errorf("unexpected number of requests: %d", got)
+	}
+}
+
+func TestNotifyInvalidEvent(t *testing.T) {
+	_, err := ecspresso.New(context.Background(), &ecspresso.CLIOptions{ConfigFilePath: "tests/notify/invalid.yml"})
+	if err == nil || !strings.Contains(err.Error(), "unknown event deploy_finished") {
+		t.Errorf("expected unknown event error, got %v", err)
+	}
+}
+
+func TestDeployCreateServiceNotifyStarted(t *testing.T) {
+	ts, requests := newNotifyTestServer(t)
+	t.Setenv("NOTIFY_URL", ts.URL)
+	app, _ := newSDKMockApp(t, "tests/notify/ecspresso.yml", map[string]any{
+		"DescribeServices":       &ecs.DescribeServicesOutput{},
+		"RegisterTaskDefinition": errors.New("invalid task definition"),
+	})
+	err := app.Deploy(context.Background(), ecspresso.DeployOption{})
+	if err == nil || !strings.Contains(err.Error(), "invalid task definition") {
+		t.Fatalf("expected the error of registering the task definition, got %v", err)
+	}
+
+	var events []string
+	for _, req := range requests() {
+		if req.Path != "/all" {
+			continue
+		}
+		var got ecspresso.Notification
+		if err := json.Unmarshal([]byte(req.Body), &got); err != nil {
+			t.Fatalf("payload is not a JSON: %s %s", err, req.Body)
+		}
+		events = append(events, got.Event)
+	}
+	if diff := cmp.Diff([]string{"deploy_started", "deploy_failed"}, events); diff != "" {
+		t.Errorf("unexpected events: %s", diff)
+	}
+}
@@ -0,0 +1,256 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/samber/lo"
+)
+
+// placementProblems returns problems of the capacity providers and the placement of the service in the cluster.
+func (d *App) placementProblems(ctx context.Context, sv *Service, td *TaskDefinitionInput) ([]error, error) {
+	out, err := d.ecs.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{d.config.Cluster},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe cluster %s: %w", d.config.Cluster, err)
+	} else if len(out.Clusters) == 0 {
+		return nil, ErrNotFound(fmt.Sprintf("cluster %s is not found", d.config.Cluster))
+	}
+	cluster := out.Clusters[0]
+
+	problems := checkCapacityProviders(sv.CapacityProviderStrategy, cluster.CapacityProviders)
+	if !usesEC2(sv, &cluster) {
+		return problems, nil
+	}
+
+	instances, err := d.listPlacementCandidates(ctx, sv, td)
+	if err != nil {
+		var pe *placementError
+		if errors.As(err, &pe) {
+			return append(problems, err), nil
+		}
+		return nil, err
+	}
+	if len(instances) == 0 {
+		if len(sv.CapacityProviderStrategy) > 0 {
+			// managed scaling of the capacity providers may launch instances
+			d.Log("[DEBUG] no container instances satisfy the placement constraints")
+			return problems, nil
+		}
+		return append(problems, errors.New("no ACTIVE container instances satisfy the placement constraints")), nil
+	}
+	if hasDistinctInstanceConstraint(sv) && sv.DesiredCount != nil {
+		if n := int32(len(instances)); n < aws.ToInt32(sv.DesiredCount) {
+			problems = append(problems, fmt.Errorf("distinctInstance placement constraint requires %d container instances, but only %d are available", aws.ToInt32(sv.DesiredCount), n))
+		}
+	}
+	cpu, memory := taskResourceRequirements(td)
+	if !instancesFitTask(instances, cpu, memory) {
+		problems = append(problems, fmt.Errorf("no container instances have enough remaining resources for the task (cpu: %d, memory: %d)", cpu, memory))
+	}
+	return problems, nil
+}
+
+type placementError struct {
+	expression string
+	err        error
+}
+
+func (e *placementError) Error() string {
+	return fmt.Sprintf("invalid placement constraint expression %q: %s", e.expression, e.err)
+}
+
+func (e *placementError) Unwrap() error {
+	return e.err
+}
+
+// listPlacementCandidates returns ACTIVE container instances which satisfy all memberOf placement constraints.
+func (d *App) listPlacementCandidates(ctx context.Context, sv *Service, td *TaskDefinitionInput) ([]types.ContainerInstance, error) {
+	var expressions []string
+	for _, c := range sv.PlacementConstraints {
+		if c.Type == types.PlacementConstraintTypeMemberOf && aws.ToString(c.Expression) != "" {
+			expressions = append(expressions, aws.ToString(c.Expression))
+		}
+	}
+	for _, c := range td.PlacementConstraints {
+		if c.Type == types.TaskDefinitionPlacementConstraintTypeMemberOf && aws.ToString(c.Expression) != "" {
+			expressions = append(expressions, aws.ToString(c.Expression))
+		}
+	}
+
+	arns, err := d.listContainerInstanceArns(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, expr := range expressions {
+		matched, err := d.listContainerInstanceArns(ctx, expr)
+		if err != nil {
+			var ex *types.InvalidParameterException
+			if errors.As(err, &ex) {
+				return nil, &placementError{expression: expr, err: err}
+			}
+			return nil, err
+		}
+		arns = lo.Intersect(arns, matched)
+	}
+
+	var instances []types.ContainerInstance
+	for _, chunk := range lo.Chunk(arns, 100) {
+		out, err := d.ecs.DescribeContainerInstances(ctx, &ecs.DescribeContainerInstancesInput{
+			Cluster:            aws.String(d.config.Cluster),
+			ContainerInstances: chunk,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe container instances: %w", err)
+		}
+		instances = append(instances, out.ContainerInstances...)
+	}
+	return instances, nil
+}
+
+func (d *App) listContainerInstanceArns(ctx context.Context, filter string) ([]string, error) {
+	in := &ecs.ListContainerInstancesInput{
+		Cluster: aws.String(d.config.Cluster),
+		Status:  types.ContainerInstanceStatusActive,
+	}
+	if filter != "" {
+		in.Filter = aws.String(filter)
+	}
+	var arns []string
+	p := ecs.NewListContainerInstancesPaginator(d.ecs, in)
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list container instances: %w", err)
+		}
+		arns = append(arns, out.ContainerInstanceArns...)
+	}
+	return arns, nil
+}
+
+// checkCapacityProviders checks that all capacity providers in the strategy are attached to the cluster.
+func checkCapacityProviders(strategy []types.CapacityProviderStrategyItem, attached []string) []error {
+	var problems []error
+	for _, s := range strategy {
+		name := aws.ToString(s.CapacityProvider)
+		if !lo.Contains(attached, name) {
+			problems = append(problems, fmt.Errorf("capacity provider %s is not attached to the cluster (attached: %s)", name, strings.Join(attached, ",")))
+		}
+	}
+	return problems
+}
+
+func isFargateCapacityProvider(name string) bool {
+	return name == "FARGATE" || name == "FARGATE_SPOT"
+}
+
+// usesEC2 reports whether tasks of the service may be placed on EC2 container instances.
+func usesEC2(sv *Service, cluster *types.Cluster) bool {
+	if sv.LaunchType != "" {
+		return sv.LaunchType == types.LaunchTypeEc2
+	}
+	strategy := sv.CapacityProviderStrategy
+	if len(strategy) == 0 {
+		strategy = cluster.DefaultCapacityProviderStrategy
+	}
+	if len(strategy) == 0 {
+		// the default launch type is EC2
+		return true
+	}
+	for _, s := range strategy {
+		if !isFargateCapacityProvider(aws.ToString(s.CapacityProvider)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDistinctInstanceConstraint(sv *Service) bool {
+	for _, c := range sv.PlacementConstraints {
+		if c.Type == types.PlacementConstraintTypeDistinctInstance {
+			return true
+		}
+	}
+	return false
+}
+
+// taskResourceRequirements returns cpu units and memory (MiB) required by the task.
+// When the task level cpu or memory is not defined, the sum of the containers is used.
+func taskResourceRequirements(td *TaskDefinitionInput) (cpu, memory int32) {
+	if v, err := strconv.Atoi(aws.ToString(toNumberCPU(aws.ToString(td.Cpu)))); err == nil {
+		cpu = int32(v)
+	} else {
+		for _, c := range td.ContainerDefinitions {
+			cpu += c.Cpu
+		}
+	}
+	if v, err := strconv.Atoi(aws.ToString(toNumberMemory(aws.ToString(td.Memory)))); err == nil {
+		memory = int32(v)
+	} else {
+		for _, c := range td.ContainerDefinitions {
+			if c.Memory != nil {
+				memory += aws.ToInt32(c.Memory)
+			} else {
+				memory += aws.ToInt32(c.MemoryReservation)
+			}
+		}
+	}
+	return cpu, memory
+}
+
+// instancesFitTask reports whether any of the container instances has enough remaining resources.
+func instancesFitTask(instances []types.ContainerInstance, cpu, memory int32) bool {
+	for _, ci := range instances {
+		var remainingCPU, remainingMemory int32
+		for _, r := range ci.RemainingResources {
+			switch aws.ToString(r.Name) {
+			case "CPU":
+				remainingCPU = r.IntegerValue
+			case "MEMORY":
+				remainingMemory = r.IntegerValue
+			}
+		}
+		if cpu <= remainingCPU && memory <= remainingMemory {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *App) verifyPlacement(ctx context.Context) error {
+	problems, err := d.placementProblems(ctx, d.verifier.sv, d.verifier.td)
+	if err != nil {
+		return err
+	}
+	return verifyErrors(problems).orNil()
+}
+
+// warnPlacement logs warnings of the placement of the service before updating the service.
+func (d *App) warnPlacement(ctx context.Context, sv *Service, tdArn string) {
+	var td *TaskDefinitionInput
+	var err error
+	if tdArn != "" {
+		td, err = d.DescribeTaskDefinition(ctx, tdArn)
+	} else {
+		td, err = d.LoadTaskDefinition(d.config.TaskDefinitionPath)
+	}
+	if err != nil {
+		d.Log("[WARNING] failed to load task definition to check placement: %s", err)
+		return
+	}
+	problems, err := d.placementProblems(ctx, sv, td)
+	if err != nil {
+		d.Log("[WARNING] failed to check placement: %s", err)
+		return
+	}
+	for _, p := range problems {
+		d.Log("[WARNING] %s", p)
+	}
+}
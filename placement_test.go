@@ -0,0 +1,146 @@
+package ecspresso_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestCheckCapacityProviders(t *testing.T) {
+	strategy := []types.CapacityProviderStrategyItem{
+		{CapacityProvider: aws.String("FARGATE")},
+		{CapacityProvider: aws.String("FARGATE_SPOT")},
+	}
+	if problems := ecspresso.CheckCapacityProviders(strategy, []string{"FARGATE", "FARGATE_SPOT"}); len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+	problems := ecspresso.CheckCapacityProviders(strategy, []string{"FARGATE"})
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got %v", problems)
+	}
+	if expected := "capacity provider FARGATE_SPOT is not attached to the cluster (attached: FARGATE)"; problems[0].Error() != expected {
+		t.Errorf("unexpected problem: %s", problems[0])
+	}
+}
+
+func TestUsesEC2(t *testing.T) {
+	fargateDefault := &types.Cluster{
+		DefaultCapacityProviderStrategy: []types.CapacityProviderStrategyItem{
+			{CapacityProvider: aws.String("FARGATE")},
+		},
+	}
+	tests := []struct {
+		name    string
+		sv      *ecspresso.Service
+		cluster *types.Cluster
+		expect  bool
+	}{
+		{"launch type EC2", &ecspresso.Service{Service: types.Service{LaunchType: types.LaunchTypeEc2}}, &types.Cluster{}, true},
+		{"launch type FARGATE", &ecspresso.Service{Service: types.Service{LaunchType: types.LaunchTypeFargate}}, &types.Cluster{}, false},
+		{"no launch type", &ecspresso.Service{}, &types.Cluster{}, true},
+		{"cluster default strategy", &ecspresso.Service{}, fargateDefault, false},
+		{
+			"EC2 capacity provider",
+			&ecspresso.Service{Service: types.Service{
+				CapacityProviderStrategy: []types.CapacityProviderStrategyItem{
+					{CapacityProvider: aws.String("FARGATE")},
+					{CapacityProvider: aws.String("my-asg")},
+				},
+			}},
+			fargateDefault,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		if got := ecspresso.UsesEC2(tt.sv, tt.cluster); got != tt.expect {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expect, got)
+		}
+	}
+}
+
+func TestTaskResourceRequirements(t *testing.T) {
+	td := &ecspresso.TaskDefinitionInput{
+		Cpu:    aws.String("0.5 vCPU"),
+		Memory: aws.String("1 GB"),
+	}
+	if cpu, memory := ecspresso.TaskResourceRequirements(td); cpu != 512 || memory != 1024 {
+		t.Errorf("unexpected requirements: cpu=%d memory=%d", cpu, memory)
+	}
+	td = &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Cpu: 256, Memory: aws.Int32(512)},
+			{Cpu: 128, MemoryReservation: aws.Int32(128)},
+		},
+	}
+	if cpu, memory := ecspresso.TaskResourceRequirements(td); cpu != 384 || memory != 640 {
+		t.Errorf("unexpected requirements: cpu=%d memory=%d", cpu, memory)
+	}
+}
+
+func TestInstancesFitTask(t *testing.T) {
+	instances := []types.ContainerInstance{
+		{RemainingResources: []types.Resource{
+			{Name: aws.String("CPU"), IntegerValue: 1024},
+			{Name: aws.String("MEMORY"), IntegerValue: 512},
+		}},
+		{RemainingResources: []types.Resource{
+			{Name: aws.String("CPU"), IntegerValue: 256},
+			{Name: aws.String("MEMORY"), IntegerValue: 2048},
+		}},
+	}
+	if !ecspresso.InstancesFitTask(instances, 512, 512) {
+		t.Error("expected to fit")
+	}
+	if ecspresso.InstancesFitTask(instances, 512, 1024) {
+		t.Error("expected not to fit")
+	}
+}
+
+func TestDeployWarnPlacement(t *testing.T) {
+	tests := []struct {
+		name   string
+		opt    ecspresso.DeployOption
+		expect int
+	}{
+		{"unchanged task definition", ecspresso.DeployOption{SkipTaskDefinition: true}, 0},
+		{"new task definition with --no-update-service", ecspresso.DeployOption{Revision: 2, UpdateService: false}, 1},
+		{"new task definition with service update", ecspresso.DeployOption{Revision: 2, UpdateService: true}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, m := newSDKMockApp(t, "tests/alarm/ecspresso.yml", map[string]any{
+				"DescribeServices": &ecs.DescribeServicesOutput{
+					Services: []types.Service{
+						{
+							ServiceName:    aws.String("test"),
+							ClusterArn:     aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/default"),
+							Status:         aws.String("ACTIVE"),
+							LaunchType:     types.LaunchTypeFargate,
+							TaskDefinition: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:1"),
+						},
+					},
+				},
+				"DescribeTaskDefinition": &ecs.DescribeTaskDefinitionOutput{
+					TaskDefinition: &types.TaskDefinition{Family: aws.String("test")},
+				},
+				"DescribeClusters": &ecs.DescribeClustersOutput{
+					Clusters: []types.Cluster{{ClusterName: aws.String("default")}},
+				},
+				"DescribeScalableTargets": &applicationautoscaling.DescribeScalableTargetsOutput{},
+			})
+			opt := tt.opt
+			opt.DryRun = true
+			if err := app.Deploy(context.Background(), opt); err != nil {
+				t.Fatal(err)
+			}
+			if n := m.count("DescribeClusters"); n != tt.expect {
+				t.Errorf("expected %d checks of the placement, got %d", tt.expect, n)
+			}
+		})
+	}
+}
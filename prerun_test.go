@@ -0,0 +1,22 @@
+package ecspresso_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestPreRunTaskDryRun(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/tags/ecspresso.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.PreRunTask(ctx, "", ecspresso.DeployOption{DryRun: true, PreRun: "tests/prerun/migrate.jsonnet"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := app.PreRunTask(ctx, "", ecspresso.DeployOption{DryRun: true, PreRun: "tests/prerun/not-found.jsonnet"}); err == nil {
+		t.Error("expected error for the missing overrides file")
+	}
+}
@@ -26,6 +26,9 @@ func (d *App) Register(ctx context.Context, opt RegisterOption) error {
 	if err != nil {
 		return err
 	}
+	if err := d.checkTagPolicy("task definition", td.Tags); err != nil {
+		return err
+	}
 	if opt.DryRun {
 		d.Log("task definition:")
 		if err := d.OutputJSONForAPI(os.Stdout, td); err != nil {
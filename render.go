@@ -14,6 +14,7 @@ import (
 type RenderOption struct {
 	Targets *[]string `arg:"" help:"target to render (config, service-definition, servicedef, task-definition, taskdef)" enum:"config,service-definition,servicedef,task-definition,taskdef"`
 	Jsonnet bool      `help:"render as jsonnet format" default:"false"`
+	Mask    bool      `help:"mask secret values of environment variables by the mask configuration" default:"false" negatable:""`
 }
 
 func (d *App) Render(ctx context.Context, opt RenderOption) error {
@@ -61,6 +62,13 @@ func (d *App) Render(ctx context.Context, opt RenderOption) error {
 				return err
 			}
 			s := MustMarshalJSONStringForAPI(td)
+			if opt.Mask {
+				b, err := d.config.masker.maskJSON([]byte(s))
+				if err != nil {
+					return err
+				}
+				s = string(b)
+			}
 			if opt.Jsonnet {
 				s, err = formatter.Format(d.config.TaskDefinitionPath, s, formatter.DefaultOptions())
 				if err != nil {
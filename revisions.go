@@ -151,6 +151,9 @@ func (d *App) dumpRevision(ctx context.Context, family string, rv string) error
 	if err != nil {
 		return err
 	}
+	if b, err = d.config.masker.maskJSON(b); err != nil {
+		return err
+	}
 	_, err = os.Stdout.Write(b)
 	return err
 }
@@ -0,0 +1,186 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/samber/lo"
+)
+
+var jsonStringLiteralRegexp = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+// rewriteVar is a value to be parameterized by an ext var in generated Jsonnet.
+type rewriteVar struct {
+	ExtVar string
+	Local  string
+	Value  string
+	// Scoped vars are rewritten only in ARNs and the values of Keys,
+	// because the values may be used as other names (container names, environment variables, etc.).
+	Scoped bool
+	Keys   []string
+}
+
+// jsonnetRewriter rewrites values in JSON which depend on an account, a region and a cluster to ext vars of Jsonnet.
+type jsonnetRewriter struct {
+	vars []rewriteVar
+}
+
+func newJsonnetRewriter(accountID, region, cluster string) *jsonnetRewriter {
+	r := &jsonnetRewriter{}
+	for _, v := range []rewriteVar{
+		{ExtVar: "ACCOUNT_ID", Local: "account_id", Value: accountID},
+		{ExtVar: "REGION", Local: "region", Value: region},
+		{ExtVar: "CLUSTER", Local: "cluster", Value: cluster, Scoped: true, Keys: []string{"cluster"}},
+	} {
+		if v.Value != "" {
+			r.vars = append(r.vars, v)
+		}
+	}
+	// match longer values first
+	sort.SliceStable(r.vars, func(i, j int) bool {
+		return len(r.vars[i].Value) > len(r.vars[j].Value)
+	})
+	return r
+}
+
+func (d *App) newJsonnetRewriter(ctx context.Context) (*jsonnetRewriter, error) {
+	out, err := sts.NewFromConfig(d.config.awsv2Config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	region := d.config.Region
+	if region == "" {
+		region = d.config.awsv2Config.Region
+	}
+	return newJsonnetRewriter(aws.ToString(out.Account), region, d.config.Cluster), nil
+}
+
+// rewriteMap returns a map of ext var names to the original values.
+func (r *jsonnetRewriter) rewriteMap() map[string]string {
+	m := make(map[string]string, len(r.vars))
+	for _, v := range r.vars {
+		m[v.ExtVar] = v.Value
+	}
+	return m
+}
+
+// rewrite rewrites string values in JSON src to Jsonnet expressions using ext vars.
+// Object keys are not rewritten.
+func (r *jsonnetRewriter) rewrite(src []byte) []byte {
+	if r == nil || len(r.vars) == 0 {
+		return src
+	}
+	used := map[string]bool{}
+	var body bytes.Buffer
+	var lastKey string
+	last := 0
+	for _, loc := range jsonStringLiteralRegexp.FindAllIndex(src, -1) {
+		start, end := loc[0], loc[1]
+		var s string
+		if err := json.Unmarshal(src[start:end], &s); err != nil {
+			continue
+		}
+		if isJSONObjectKey(src[end:]) {
+			lastKey = s
+			continue
+		}
+		var key string
+		if isJSONObjectValue(src[:start]) {
+			key = lastKey
+		}
+		expr, ok := r.expression(s, key, used)
+		if !ok {
+			continue
+		}
+		body.Write(src[last:start])
+		body.WriteString(expr)
+		last = end
+	}
+	body.Write(src[last:])
+
+	var b bytes.Buffer
+	for _, v := range r.vars {
+		if used[v.Local] {
+			fmt.Fprintf(&b, "local %s = std.extVar('%s');\n", v.Local, v.ExtVar)
+		}
+	}
+	b.Write(body.Bytes())
+	return b.Bytes()
+}
+
+// expression returns a Jsonnet expression which concatenates parts of s and ext vars.
+// key is the object key of s, or empty for elements of arrays.
+func (r *jsonnetRewriter) expression(s, key string, used map[string]bool) (string, bool) {
+	var parts []string
+	var buf strings.Builder
+	found := false
+	isArn := strings.HasPrefix(s, "arn:")
+	for i := 0; i < len(s); {
+		v, ok := r.matchAt(s, i, func(v rewriteVar) bool {
+			return !v.Scoped || isArn || lo.Contains(v.Keys, key)
+		})
+		if !ok {
+			buf.WriteByte(s[i])
+			i++
+			continue
+		}
+		found = true
+		if buf.Len() > 0 {
+			parts = append(parts, quoteJsonnetString(buf.String()))
+			buf.Reset()
+		}
+		parts = append(parts, v.Local)
+		used[v.Local] = true
+		i += len(v.Value)
+	}
+	if !found {
+		return "", false
+	}
+	if buf.Len() > 0 {
+		parts = append(parts, quoteJsonnetString(buf.String()))
+	}
+	return strings.Join(parts, " + "), true
+}
+
+// matchAt finds a var in scope whose value appears at s[i:] as a whole word.
+func (r *jsonnetRewriter) matchAt(s string, i int, inScope func(rewriteVar) bool) (rewriteVar, bool) {
+	if i > 0 && isRewriteWordChar(s[i-1]) {
+		return rewriteVar{}, false
+	}
+	for _, v := range r.vars {
+		if !inScope(v) || !strings.HasPrefix(s[i:], v.Value) {
+			continue
+		}
+		if e := i + len(v.Value); e < len(s) && isRewriteWordChar(s[e]) {
+			continue
+		}
+		return v, true
+	}
+	return rewriteVar{}, false
+}
+
+func isRewriteWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+func isJSONObjectKey(rest []byte) bool {
+	rest = bytes.TrimLeft(rest, " \t\r\n")
+	return len(rest) > 0 && rest[0] == ':'
+}
+
+func isJSONObjectValue(prev []byte) bool {
+	prev = bytes.TrimRight(prev, " \t\r\n")
+	return len(prev) > 0 && prev[len(prev)-1] == ':'
+}
+
+func quoteJsonnetString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
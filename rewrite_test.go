@@ -0,0 +1,109 @@
+package ecspresso_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-jsonnet"
+	"github.com/kayac/ecspresso/v2"
+)
+
+var testRewriteSrc = `{
+  "cluster": "prod",
+  "clusterArn": "arn:aws:ecs:ap-northeast-1:123456789012:cluster/prod",
+  "containerDefinitions": [
+    {
+      "name": "prod",
+      "environment": [
+        {"name": "APP_ENV", "value": "prod"}
+      ]
+    }
+  ],
+  "executionRoleArn": "arn:aws:iam::123456789012:role/ecsTaskExecutionRole",
+  "image": "123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:latest",
+  "logConfiguration": {
+    "options": {
+      "awslogs-group": "/ecs/prod/app",
+      "awslogs-region": "ap-northeast-1"
+    }
+  },
+  "prod": "production",
+  "productionName": "prod-42",
+  "targetGroupArn": "arn:aws:elasticloadbalancing:ap-northeast-1:123456789012:targetgroup/app/abcdef"
+}
+`
+
+func TestRewriteJsonnet(t *testing.T) {
+	b, m := ecspresso.RewriteJsonnet("123456789012", "ap-northeast-1", "prod", []byte(testRewriteSrc))
+	if diff := cmp.Diff(m, map[string]string{
+		"ACCOUNT_ID": "123456789012",
+		"REGION":     "ap-northeast-1",
+		"CLUSTER":    "prod",
+	}); diff != "" {
+		t.Errorf("unexpected rewrite map: %s", diff)
+	}
+	if strings.Contains(string(b), "123456789012") {
+		t.Errorf("account ID is not rewritten: %s", b)
+	}
+
+	eval := func(vars map[string]string) map[string]interface{} {
+		vm := jsonnet.MakeVM()
+		for k, v := range vars {
+			vm.ExtVar(k, v)
+		}
+		out, err := vm.EvaluateAnonymousSnippet("rewrite.jsonnet", string(b))
+		if err != nil {
+			t.Fatalf("failed to evaluate %s: %s", b, err)
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(out), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	// evaluating with the rewrite map reproduces the original
+	var orig map[string]interface{}
+	if err := json.Unmarshal([]byte(testRewriteSrc), &orig); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(orig, eval(m)); diff != "" {
+		t.Errorf("unexpected result with the original values: %s", diff)
+	}
+
+	// evaluating with other values clones the definition
+	cloned := eval(map[string]string{
+		"ACCOUNT_ID": "210987654321",
+		"REGION":     "us-west-2",
+		"CLUSTER":    "dr",
+	})
+	expected := map[string]interface{}{
+		"cluster":    "dr",
+		"clusterArn": "arn:aws:ecs:us-west-2:210987654321:cluster/dr",
+		// the cluster name is rewritten only in ARNs and cluster fields
+		"containerDefinitions": []interface{}{
+			map[string]interface{}{
+				"name": "prod",
+				"environment": []interface{}{
+					map[string]interface{}{"name": "APP_ENV", "value": "prod"},
+				},
+			},
+		},
+		"executionRoleArn": "arn:aws:iam::210987654321:role/ecsTaskExecutionRole",
+		"image":            "210987654321.dkr.ecr.us-west-2.amazonaws.com/app:latest",
+		"logConfiguration": map[string]interface{}{
+			"options": map[string]interface{}{
+				"awslogs-group":  "/ecs/prod/app",
+				"awslogs-region": "us-west-2",
+			},
+		},
+		"prod":           "production", // keys are not rewritten
+		"productionName": "prod-42",
+		"targetGroupArn": "arn:aws:elasticloadbalancing:us-west-2:210987654321:targetgroup/app/abcdef",
+	}
+	if diff := cmp.Diff(expected, cloned); diff != "" {
+		t.Errorf("unexpected result with other values: %s", diff)
+	}
+}
@@ -21,6 +21,8 @@ type RollbackOption struct {
 	DeregisterTaskDefinition bool   `help:"deregister the rolled-back task definition. not works with --no-wait" default:"true" negatable:""`
 	Wait                     bool   `help:"wait for the service stable" default:"true" negatable:""`
 	RollbackEvents           string `help:"roll back when specified events happened (DEPLOYMENT_FAILURE,DEPLOYMENT_STOP_ON_ALARM,DEPLOYMENT_STOP_ON_REQUEST,...) CodeDeploy only." default:""`
+
+	targetArn string `kong:"-"` // the task definition to roll back to, instead of finding it
 }
 
 func (opt RollbackOption) DryRunString() string {
@@ -38,6 +40,30 @@ func (d *App) Rollback(ctx context.Context, opt RollbackOption) error {
 		return fmt.Errorf("--deregister-task-definition not works with --no-wait together. Please use --no-deregister-task-definition with --no-wait")
 	}
 
+	if !opt.DryRun {
+		unlock, err := d.acquireLock(ctx, "rollback")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	return d.rollback(ctx, opt)
+}
+
+// rollback rolls back the service. The caller must hold the deployment lock.
+func (d *App) rollback(ctx context.Context, opt RollbackOption) (err error) {
+	if !opt.DryRun {
+		var n *Notification
+		ctx, n = d.startNotification(ctx)
+		defer func() {
+			d.notifyStarted(ctx, eventRollbackStarted)
+			d.notifyResult(ctx, n, err, eventRollbackSucceeded, eventRollbackFailed)
+		}()
+
+		ctx, _ = d.startHooks(ctx, "rollback")
+		defer func() { d.runFailureHooks(ctx, err) }()
+	}
+
 	d.Log("Starting rollback %s", opt.DryRunString())
 	sv, err := d.DescribeServiceStatus(ctx, 0)
 	if err != nil {
@@ -49,15 +75,26 @@ func (d *App) Rollback(ctx context.Context, opt RollbackOption) error {
 	if err != nil {
 		return err
 	}
-	targetArn, err := d.FindRollbackTarget(ctx, *sv.TaskDefinition)
-	if err != nil {
-		return err
+	targetArn := opt.targetArn
+	if targetArn == "" {
+		if targetArn, err = d.rollbackTarget(ctx, sv); err != nil {
+			return err
+		}
+	}
+	recordTaskDefinition(ctx, *sv.TaskDefinition, targetArn)
+	if !opt.DryRun {
+		d.notifyStarted(ctx, eventRollbackStarted)
 	}
 	doWait, err := d.WaitFunc(sv, d.confirmPrimaryTD(targetArn))
 	if err != nil {
 		return err
 	}
 
+	if !opt.DryRun {
+		if err := d.runHooks(ctx, hookPreRollback); err != nil {
+			return err
+		}
+	}
 	// doRollback returns the task definition arn to be rolled back
 	rollbackedTdArn, err := doRollback(ctx, sv, targetArn, opt)
 	if err != nil {
@@ -91,6 +128,21 @@ func (d *App) Rollback(ctx context.Context, opt RollbackOption) error {
 	return d.rollbackTaskDefinition(ctx, rollbackedTdArn, opt)
 }
 
+// rollbackTarget returns the task definition arn which the service is rolled back to.
+func (d *App) rollbackTarget(ctx context.Context, sv *Service) (string, error) {
+	if sv.isBlueGreen() {
+		// the deployment in progress is rolled back to its source revision
+		tdArn, err := d.blueGreenRollbackTarget(ctx)
+		if err == nil {
+			return tdArn, nil
+		} else if !errors.As(err, &errNotFound) {
+			return "", err
+		}
+		d.Log("[DEBUG] %s", err)
+	}
+	return d.FindRollbackTarget(ctx, *sv.TaskDefinition)
+}
+
 func (d *App) rollbackTaskDefinition(ctx context.Context, rollbackedTdArn string, opt RollbackOption) error {
 	if !opt.DeregisterTaskDefinition {
 		return nil
@@ -236,6 +288,9 @@ type rollbackFunc func(ctx context.Context, sv *Service, targetArn string, opt R
 
 func (d *App) RollbackFunc(sv *Service) (rollbackFunc, error) {
 	defaultFunc := d.RollbackServiceTasks
+	if sv != nil && sv.isBlueGreen() {
+		defaultFunc = d.RollbackBlueGreen
+	}
 	if sv == nil || sv.DeploymentController == nil {
 		return defaultFunc, nil
 	}
@@ -244,7 +299,9 @@ func (d *App) RollbackFunc(sv *Service) (rollbackFunc, error) {
 		case types.DeploymentControllerTypeCodeDeploy:
 			return d.RollbackByCodeDeploy, nil
 		case types.DeploymentControllerTypeEcs:
-			return d.RollbackServiceTasks, nil
+			return defaultFunc, nil
+		case types.DeploymentControllerTypeExternal:
+			return d.RollbackByTaskSet, nil
 		default:
 			return nil, fmt.Errorf("unsupported deployment controller type: %s", dc.Type)
 		}
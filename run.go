@@ -52,11 +52,7 @@ func (d *App) Run(ctx context.Context, opt RunOption) error {
 			return fmt.Errorf("invalid overrides: %w", err)
 		}
 	} else if ovFile := opt.TaskOverrideFile; ovFile != "" {
-		src, err := d.readDefinitionFile(ovFile)
-		if err != nil {
-			return fmt.Errorf("failed to read overrides-file %s: %w", ovFile, err)
-		}
-		if err := unmarshalJSON(src, &ov, ovFile); err != nil {
+		if err := d.loadTaskOverrideFile(ovFile, &ov); err != nil {
 			return fmt.Errorf("failed to read overrides-file %s: %w", ovFile, err)
 		}
 	}
@@ -98,6 +94,57 @@ func (d *App) Run(ctx context.Context, opt RunOption) error {
 	return nil
 }
 
+func (d *App) loadTaskOverrideFile(path string, ov *types.TaskOverride) error {
+	src, err := d.readDefinitionFile(path)
+	if err != nil {
+		return err
+	}
+	return unmarshalJSON(src, ov, path)
+}
+
+// preRunTask runs a one-off task with the task definition to be deployed and waits for the task stopped.
+func (d *App) preRunTask(ctx context.Context, tdArn string, opt DeployOption) error {
+	ov := types.TaskOverride{}
+	if err := d.loadTaskOverrideFile(opt.PreRun, &ov); err != nil {
+		return fmt.Errorf("failed to read pre-run overrides %s: %w", opt.PreRun, err)
+	}
+	d.Log("[DEBUG] pre-run overrides")
+	d.LogJSON(ov)
+	if opt.DryRun {
+		d.Log("pre-run task will be run with overrides %s", opt.PreRun)
+		return nil
+	}
+
+	d.Log("Running pre-run task with overrides %s", opt.PreRun)
+	if err := d.runTaskAndWait(ctx, tdArn, &ov, opt.PreRunWatchContainer); err != nil {
+		return fmt.Errorf("pre-run task failed: %w", err)
+	}
+	d.Log("Pre-run task completed!")
+	return nil
+}
+
+// runTaskAndWait runs a task and waits for the task stopped.
+// It returns an error when the watched container does not exit with 0.
+func (d *App) runTaskAndWait(ctx context.Context, tdArn string, ov *types.TaskOverride, container string) error {
+	td, err := d.DescribeTaskDefinition(ctx, tdArn)
+	if err != nil {
+		return err
+	}
+	watchContainer := containerOf(td, &container)
+	if watchContainer == nil {
+		return fmt.Errorf("container %s is not found in the task definition %s", container, tdArn)
+	}
+	d.Log("Watch container: %s", *watchContainer.Name)
+	task, err := d.RunTask(ctx, tdArn, ov, &RunOption{Count: 1})
+	if err != nil {
+		return err
+	}
+	if err := d.WaitRunTask(ctx, task, watchContainer, time.Now(), false); err != nil {
+		return err
+	}
+	return d.DescribeTaskStatus(ctx, task, watchContainer)
+}
+
 func (d *App) RunTask(ctx context.Context, tdArn string, ov *types.TaskOverride, opt *RunOption) (*types.Task, error) {
 	d.Log("Running task with %s", tdArn)
 
@@ -150,6 +197,10 @@ func (d *App) RunTask(ctx context.Context, tdArn string, ov *types.TaskOverride,
 	default:
 		in.PropagateTags = types.PropagateTagsTaskDefinition
 	}
+	if in.PropagateTags != types.PropagateTagsTaskDefinition {
+		// default tags are propagated from the task definition
+		in.Tags = d.config.Tags.applyDefaults(in.Tags)
+	}
 	d.Log("[DEBUG] run task input")
 	d.LogJSON(in)
 
@@ -281,6 +332,7 @@ func (d *App) taskDefinitionArnForRun(ctx context.Context, opt RunOption) (strin
 		}
 		{
 			b, _ := MarshalJSONForAPI(in)
+			b, _ = d.config.masker.maskJSON(b)
 			d.Log("[DEBUG] task definition: %s", string(b))
 		}
 		if opt.DryRun {
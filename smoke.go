@@ -0,0 +1,294 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+)
+
+const (
+	defaultSmokeTestInterval = 5 * time.Second
+	defaultSmokeTestTimeout  = 10 * time.Second
+)
+
+// ConfigSmokeTest represents checks of the service after the deployment is completed.
+type ConfigSmokeTest struct {
+	HTTP     []*SmokeTestHTTP `yaml:"http,omitempty" json:"http,omitempty"`
+	Run      *SmokeTestRun    `yaml:"run,omitempty" json:"run,omitempty"`
+	Retries  int              `yaml:"retries,omitempty" json:"retries,omitempty"`
+	Interval *Duration        `yaml:"interval,omitempty" json:"interval,omitempty"`
+	Timeout  *Duration        `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Rollback bool             `yaml:"rollback,omitempty" json:"rollback,omitempty"`
+}
+
+// SmokeTestHTTP represents an HTTP check.
+// When URL is empty, the request is sent to the load balancer of the service.
+type SmokeTestHTTP struct {
+	URL            string            `yaml:"url,omitempty" json:"url,omitempty"`
+	Scheme         string            `yaml:"scheme,omitempty" json:"scheme,omitempty"`
+	Port           int               `yaml:"port,omitempty" json:"port,omitempty"`
+	Path           string            `yaml:"path,omitempty" json:"path,omitempty"`
+	Method         string            `yaml:"method,omitempty" json:"method,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Body           string            `yaml:"body,omitempty" json:"body,omitempty"`
+	ExpectedStatus int               `yaml:"expected_status,omitempty" json:"expected_status,omitempty"`
+	ExpectedBody   string            `yaml:"expected_body,omitempty" json:"expected_body,omitempty"`
+
+	expectedBody *regexp.Regexp
+}
+
+// SmokeTestRun represents a task to be run as a smoke test.
+type SmokeTestRun struct {
+	TaskDefinition string `yaml:"task_definition,omitempty" json:"task_definition,omitempty"`
+	OverridesFile  string `yaml:"overrides_file,omitempty" json:"overrides_file,omitempty"`
+	WatchContainer string `yaml:"watch_container,omitempty" json:"watch_container,omitempty"`
+}
+
+func (c *ConfigSmokeTest) setup(dir string) error {
+	if c == nil {
+		return nil
+	}
+	if len(c.HTTP) == 0 && c.Run == nil {
+		return fmt.Errorf("smoke_test: http or run is required")
+	}
+	if c.Interval == nil {
+		c.Interval = &Duration{Duration: defaultSmokeTestInterval}
+	}
+	if c.Timeout == nil {
+		c.Timeout = &Duration{Duration: defaultSmokeTestTimeout}
+	}
+	for _, h := range c.HTTP {
+		if h.ExpectedStatus == 0 {
+			h.ExpectedStatus = http.StatusOK
+		}
+		if h.ExpectedBody != "" {
+			re, err := regexp.Compile(h.ExpectedBody)
+			if err != nil {
+				return fmt.Errorf("smoke_test: invalid expected_body: %w", err)
+			}
+			h.expectedBody = re
+		}
+	}
+	if r := c.Run; r != nil {
+		if r.TaskDefinition != "" {
+			r.TaskDefinition = filepath.Join(dir, r.TaskDefinition)
+		}
+		if r.OverridesFile != "" {
+			r.OverridesFile = filepath.Join(dir, r.OverridesFile)
+		}
+	}
+	return nil
+}
+
+func (h *SmokeTestHTTP) String() string {
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	if h.URL != "" {
+		return method + " " + h.URL
+	}
+	return method + " " + h.Path + " (load balancer)"
+}
+
+// check sends a request to url and checks the response.
+func (h *SmokeTestHTTP) check(ctx context.Context, client *http.Client, url string) error {
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if h.Body != "" {
+		body = strings.NewReader(h.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "ecspresso/"+Version)
+	for k, v := range h.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the response body: %w", err)
+	}
+	if resp.StatusCode != h.ExpectedStatus {
+		return fmt.Errorf("unexpected status %d (expected %d)", resp.StatusCode, h.ExpectedStatus)
+	}
+	if h.expectedBody != nil && !h.expectedBody.Match(b) {
+		return fmt.Errorf("the response body does not match %s", h.ExpectedBody)
+	}
+	return nil
+}
+
+// runSmokeTest runs the smoke test of the deployed service, and rolls back the service on failure if configured.
+func (d *App) runSmokeTest(ctx context.Context, tdArn string, sv *Service, opt DeployOption) error {
+	c := d.config.SmokeTest
+	if c == nil || !opt.SmokeTest {
+		return nil
+	}
+	d.Log("Running smoke test")
+	err := d.smokeTest(ctx, tdArn, sv)
+	if err == nil {
+		d.Log("Smoke test passed")
+		return nil
+	}
+	if !c.Rollback {
+		return fmt.Errorf("smoke test failed: %w", err)
+	}
+	d.Log("[WARNING] smoke test failed: %s", err)
+	d.Log("Rolling back the service")
+	ropt := RollbackOption{DeregisterTaskDefinition: true, Wait: true}
+	if rerr := d.audit(ctx, "rollback", false, func(ctx context.Context) error {
+		return d.rollback(ctx, ropt)
+	}); rerr != nil {
+		return fmt.Errorf("smoke test failed: %s, and failed to roll back: %w", err, rerr)
+	}
+	return fmt.Errorf("smoke test failed and the service was rolled back: %w", err)
+}
+
+func (d *App) smokeTest(ctx context.Context, tdArn string, sv *Service) error {
+	c := d.config.SmokeTest
+	if len(c.HTTP) > 0 {
+		if err := d.httpSmokeTest(ctx, sv); err != nil {
+			return err
+		}
+	}
+	if c.Run != nil {
+		if err := d.runTaskSmokeTest(ctx, tdArn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *App) httpSmokeTest(ctx context.Context, sv *Service) error {
+	c := d.config.SmokeTest
+	client := &http.Client{Timeout: c.Timeout.Duration}
+	var lbHost string
+	for _, h := range c.HTTP {
+		url := h.URL
+		if url == "" {
+			if lbHost == "" {
+				var err error
+				if lbHost, err = d.loadBalancerDNSName(ctx, sv); err != nil {
+					return err
+				}
+			}
+			url = loadBalancerURL(lbHost, h)
+		}
+		var err error
+		for i := 0; i <= c.Retries; i++ {
+			if i > 0 {
+				d.Log("[INFO] %s: %s. retrying after %s", h, err, c.Interval.Duration)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(c.Interval.Duration):
+				}
+			}
+			d.Log("[DEBUG] smoke test %s %s", h, url)
+			if err = h.check(ctx, client, url); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", h, err)
+		}
+		d.Log("[INFO] %s: OK", h)
+	}
+	return nil
+}
+
+func loadBalancerURL(host string, h *SmokeTestHTTP) string {
+	scheme := h.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	if h.Port != 0 {
+		host = fmt.Sprintf("%s:%d", host, h.Port)
+	}
+	path := h.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
+
+// loadBalancerDNSName returns the DNS name of the load balancer associated with the target group of the service.
+func (d *App) loadBalancerDNSName(ctx context.Context, sv *Service) (string, error) {
+	var tgArn string
+	for _, lb := range sv.LoadBalancers {
+		if lb.TargetGroupArn != nil {
+			tgArn = *lb.TargetGroupArn
+			break
+		}
+	}
+	if tgArn == "" {
+		return "", fmt.Errorf("no target groups are associated with the service")
+	}
+	tgs, err := d.elbv2.DescribeTargetGroups(ctx, &elasticloadbalancingv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []string{tgArn},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe target group %s: %w", tgArn, err)
+	}
+	if len(tgs.TargetGroups) == 0 || len(tgs.TargetGroups[0].LoadBalancerArns) == 0 {
+		return "", fmt.Errorf("no load balancers are associated with the target group %s", tgArn)
+	}
+	lbs, err := d.elbv2.DescribeLoadBalancers(ctx, &elasticloadbalancingv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: tgs.TargetGroups[0].LoadBalancerArns[:1],
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe load balancers: %w", err)
+	}
+	if len(lbs.LoadBalancers) == 0 {
+		return "", fmt.Errorf("load balancer %s is not found", tgs.TargetGroups[0].LoadBalancerArns[0])
+	}
+	return aws.ToString(lbs.LoadBalancers[0].DNSName), nil
+}
+
+func (d *App) runTaskSmokeTest(ctx context.Context, tdArn string) error {
+	r := d.config.SmokeTest.Run
+	if r.TaskDefinition != "" {
+		td, err := d.LoadTaskDefinition(r.TaskDefinition)
+		if err != nil {
+			return err
+		}
+		newTd, err := d.RegisterTaskDefinition(ctx, td)
+		if err != nil {
+			return err
+		}
+		tdArn = aws.ToString(newTd.TaskDefinitionArn)
+	}
+	ov := types.TaskOverride{}
+	if r.OverridesFile != "" {
+		if err := d.loadTaskOverrideFile(r.OverridesFile, &ov); err != nil {
+			return fmt.Errorf("failed to read overrides_file %s: %w", r.OverridesFile, err)
+		}
+	}
+	d.Log("Running smoke test task with %s", tdArn)
+	if err := d.runTaskAndWait(ctx, tdArn, &ov, r.WatchContainer); err != nil {
+		return fmt.Errorf("smoke test task failed: %w", err)
+	}
+	return nil
+}
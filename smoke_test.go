@@ -0,0 +1,83 @@
+package ecspresso_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestHTTPSmokeTest(t *testing.T) {
+	var healthRequests, unhealthy int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			n := atomic.AddInt32(&healthRequests, 1)
+			if r.Host != "app.example.com" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if n <= atomic.LoadInt32(&unhealthy) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			io.WriteString(w, `{"status": "ok"}`)
+		case "/items":
+			b, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(b) != `{"name":"smoke"}` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("SMOKE_TEST_URL", ts.URL)
+
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/smoke/ecspresso.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.HTTPSmokeTest(ctx); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// recovered by retries
+	atomic.StoreInt32(&healthRequests, 0)
+	atomic.StoreInt32(&unhealthy, 2)
+	if err := app.HTTPSmokeTest(ctx); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// retries exceeded
+	atomic.StoreInt32(&healthRequests, 0)
+	atomic.StoreInt32(&unhealthy, 3)
+	if err := app.HTTPSmokeTest(ctx); err == nil {
+		t.Error("expected error")
+	} else if !strings.Contains(err.Error(), "unexpected status 503") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestLoadBalancerURL(t *testing.T) {
+	host := "my-alb-123456789.ap-northeast-1.elb.amazonaws.com"
+	for _, c := range []struct {
+		h    *ecspresso.SmokeTestHTTP
+		want string
+	}{
+		{&ecspresso.SmokeTestHTTP{Path: "/health"}, "http://" + host + "/health"},
+		{&ecspresso.SmokeTestHTTP{Path: "health", Scheme: "https", Port: 8443}, "https://" + host + ":8443/health"},
+	} {
+		if got := ecspresso.LoadBalancerURL(host, c.h); got != c.want {
+			t.Errorf("unexpected url %s, expected %s", got, c.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	aasTypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+)
+
+const (
+	snapshotConfigFile      = "ecspresso.yml"
+	snapshotServiceDefFile  = "ecs-service-def.json"
+	snapshotTaskDefFile     = "ecs-task-def.json"
+	snapshotAutoScalingFile = "ecs-autoscaling.json"
+)
+
+type SnapshotOption struct {
+	Dir string `help:"directory to save the snapshot (default: snapshot-{service}-{timestamp})" default:""`
+}
+
+type RestoreOption struct {
+	Dir         string `arg:"" help:"directory of the snapshot"`
+	DryRun      bool   `help:"dry run" default:"false"`
+	Cluster     string `help:"ECS cluster name to restore the service into (default: the cluster in the snapshot)" default:""`
+	Service     string `help:"ECS service name to restore the service as (default: the service in the snapshot)" default:""`
+	AutoScaling bool   `help:"restore application auto-scaling scalable targets and scaling policies" default:"true" negatable:""`
+	Wait        bool   `help:"wait for service stable" default:"true" negatable:""`
+}
+
+func (opt RestoreOption) DryRunString() string {
+	if opt.DryRun {
+		return dryRunStr
+	}
+	return ""
+}
+
+func (opt RestoreOption) configFilePath() string {
+	return filepath.Join(opt.Dir, snapshotConfigFile)
+}
+
+// snapshotAutoScaling represents application auto-scaling settings of the service in a snapshot.
+type snapshotAutoScaling struct {
+	ScalableTargets []aasTypes.ScalableTarget `json:"scalableTargets,omitempty"`
+	ScalingPolicies []aasTypes.ScalingPolicy  `json:"scalingPolicies,omitempty"`
+}
+
+func (d *App) Snapshot(ctx context.Context, opt SnapshotOption) error {
+	ctx, cancel := d.Start(ctx)
+	defer cancel()
+
+	dir := opt.Dir
+	if dir == "" {
+		dir = fmt.Sprintf("snapshot-%s-%s", d.Service, time.Now().Format("20060102-150405"))
+	}
+	return d.saveSnapshot(ctx, dir)
+}
+
+// saveSnapshot saves the config, service and task definition files of the service as `init` does,
+// and the application auto-scaling settings of the service.
+func (d *App) saveSnapshot(ctx context.Context, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory %s: %w", dir, err)
+	}
+	conf := *d.config
+	conf.ServiceDefinitionPath = filepath.Join(dir, snapshotServiceDefFile)
+	conf.TaskDefinitionPath = filepath.Join(dir, snapshotTaskDefFile)
+	snap := *d
+	snap.config = &conf
+
+	opt := InitOption{ForceOverwrite: true}
+	sv, tdArn, err := snap.initServiceDefinition(ctx, opt)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot of the service: %w", err)
+	}
+	td, err := snap.initTaskDefinition(ctx, opt, tdArn)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot of the task definition: %w", err)
+	}
+	// definition files are placed at the same directory of the config
+	conf.ServiceDefinitionPath = filepath.Base(conf.ServiceDefinitionPath)
+	conf.TaskDefinitionPath = filepath.Base(conf.TaskDefinitionPath)
+	if err := snap.initConfigurationFile(ctx, filepath.Join(dir, snapshotConfigFile), opt, sv, td); err != nil {
+		return fmt.Errorf("failed to save snapshot of the config: %w", err)
+	}
+	if err := d.saveAutoScalingSnapshot(ctx, filepath.Join(dir, snapshotAutoScalingFile)); err != nil {
+		return fmt.Errorf("failed to save snapshot of the auto scaling: %w", err)
+	}
+	d.Log("snapshot of the service is saved to %s", dir)
+	return nil
+}
+
+func (d *App) serviceResourceId() string {
+	return fmt.Sprintf("service/%s/%s", d.Cluster, d.Service)
+}
+
+func (d *App) saveAutoScalingSnapshot(ctx context.Context, path string) error {
+	resourceId := d.serviceResourceId()
+	tout, err := d.autoScaling.DescribeScalableTargets(ctx, &applicationautoscaling.DescribeScalableTargetsInput{
+		ResourceIds:       []string{resourceId},
+		ServiceNamespace:  aasTypes.ServiceNamespaceEcs,
+		ScalableDimension: aasTypes.ScalableDimensionECSServiceDesiredCount,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe scalable targets: %w", err)
+	}
+	if len(tout.ScalableTargets) == 0 {
+		d.Log("[DEBUG] no scalable target for %s", resourceId)
+		return nil
+	}
+	as := snapshotAutoScaling{ScalableTargets: tout.ScalableTargets}
+	p := applicationautoscaling.NewDescribeScalingPoliciesPaginator(d.autoScaling, &applicationautoscaling.DescribeScalingPoliciesInput{
+		ResourceId:        aws.String(resourceId),
+		ServiceNamespace:  aasTypes.ServiceNamespaceEcs,
+		ScalableDimension: aasTypes.ScalableDimensionECSServiceDesiredCount,
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe scaling policies: %w", err)
+		}
+		as.ScalingPolicies = append(as.ScalingPolicies, out.ScalingPolicies...)
+	}
+	b, err := MarshalJSONForAPI(as)
+	if err != nil {
+		return fmt.Errorf("unable to marshal auto scaling settings to JSON: %w", err)
+	}
+	d.Log("save the auto scaling settings of %s to %s", resourceId, path)
+	return d.saveFile(path, b, CreateFileMode, true)
+}
+
+func loadSnapshotAutoScaling(path string) (*snapshotAutoScaling, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var as snapshotAutoScaling
+	if err := UnmarshalJSONForStruct(src, &as, path); err != nil {
+		return nil, fmt.Errorf("failed to load auto scaling settings %s: %w", path, err)
+	}
+	return &as, nil
+}
+
+// registerScalableTargetInputs returns inputs to register the scalable targets in the snapshot for resourceId.
+func (as *snapshotAutoScaling) registerScalableTargetInputs(resourceId string) []*applicationautoscaling.RegisterScalableTargetInput {
+	ins := make([]*applicationautoscaling.RegisterScalableTargetInput, 0, len(as.ScalableTargets))
+	for _, t := range as.ScalableTargets {
+		ins = append(ins, &applicationautoscaling.RegisterScalableTargetInput{
+			ResourceId:        aws.String(resourceId),
+			ScalableDimension: t.ScalableDimension,
+			ServiceNamespace:  t.ServiceNamespace,
+			MinCapacity:       t.MinCapacity,
+			MaxCapacity:       t.MaxCapacity,
+			SuspendedState:    t.SuspendedState,
+		})
+	}
+	return ins
+}
+
+// putScalingPolicyInputs returns inputs to put the scaling policies in the snapshot for resourceId.
+func (as *snapshotAutoScaling) putScalingPolicyInputs(resourceId string) []*applicationautoscaling.PutScalingPolicyInput {
+	ins := make([]*applicationautoscaling.PutScalingPolicyInput, 0, len(as.ScalingPolicies))
+	for _, p := range as.ScalingPolicies {
+		ins = append(ins, &applicationautoscaling.PutScalingPolicyInput{
+			PolicyName:                               p.PolicyName,
+			PolicyType:                               p.PolicyType,
+			ResourceId:                               aws.String(resourceId),
+			ScalableDimension:                        p.ScalableDimension,
+			ServiceNamespace:                         p.ServiceNamespace,
+			StepScalingPolicyConfiguration:           p.StepScalingPolicyConfiguration,
+			TargetTrackingScalingPolicyConfiguration: p.TargetTrackingScalingPolicyConfiguration,
+		})
+	}
+	return ins
+}
+
+func (d *App) Restore(ctx context.Context, opt RestoreOption) error {
+	if opt.Cluster != "" {
+		d.Cluster = opt.Cluster
+		d.config.Cluster = opt.Cluster
+	}
+	if opt.Service != "" {
+		d.Service = opt.Service
+		d.config.Service = opt.Service
+	}
+	d.Log("Restoring service %s in cluster %s from %s %s", d.Service, d.Cluster, opt.Dir, opt.DryRunString())
+
+	var as *snapshotAutoScaling
+	if opt.AutoScaling {
+		var err error
+		as, err = loadSnapshotAutoScaling(filepath.Join(opt.Dir, snapshotAutoScalingFile))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			d.Log("[DEBUG] no auto scaling settings in the snapshot")
+		}
+	}
+
+	if err := d.Deploy(ctx, DeployOption{
+		DryRun:        opt.DryRun,
+		DesiredCount:  aws.Int32(DefaultDesiredCount),
+		Wait:          opt.Wait,
+		UpdateService: true,
+	}); err != nil {
+		return err
+	}
+	if as == nil {
+		return nil
+	}
+
+	ctx, cancel := d.Start(ctx)
+	defer cancel()
+	resourceId := d.serviceResourceId()
+	for _, in := range as.registerScalableTargetInputs(resourceId) {
+		d.Log("Register scalable target %s min:%d max:%d %s", resourceId, aws.ToInt32(in.MinCapacity), aws.ToInt32(in.MaxCapacity), opt.DryRunString())
+		if opt.DryRun {
+			continue
+		}
+		if _, err := d.autoScaling.RegisterScalableTarget(ctx, in); err != nil {
+			return fmt.Errorf("failed to register scalable target %s: %w", resourceId, err)
+		}
+	}
+	for _, in := range as.putScalingPolicyInputs(resourceId) {
+		d.Log("Put scaling policy %s (%s) %s", aws.ToString(in.PolicyName), in.PolicyType, opt.DryRunString())
+		if in.PolicyType == aasTypes.PolicyTypeStepScaling {
+			d.Log("[WARNING] CloudWatch alarms for the step scaling policy %s are not restored. Configure them manually", aws.ToString(in.PolicyName))
+		}
+		if opt.DryRun {
+			continue
+		}
+		if _, err := d.autoScaling.PutScalingPolicy(ctx, in); err != nil {
+			return fmt.Errorf("failed to put scaling policy %s: %w", aws.ToString(in.PolicyName), err)
+		}
+	}
+	return nil
+}
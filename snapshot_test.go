@@ -0,0 +1,51 @@
+package ecspresso_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aasTypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestRestoreAutoScalingInputs(t *testing.T) {
+	resourceId := "service/other/clone"
+	targets, policies, err := ecspresso.RestoreInputs("tests/snapshot/ecs-autoscaling.json", resourceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 1 {
+		t.Fatalf("unexpected number of scalable targets: %d", len(targets))
+	}
+	tg := targets[0]
+	if aws.ToString(tg.ResourceId) != resourceId {
+		t.Errorf("unexpected resource id: %s", aws.ToString(tg.ResourceId))
+	}
+	if aws.ToInt32(tg.MinCapacity) != 2 || aws.ToInt32(tg.MaxCapacity) != 10 {
+		t.Errorf("unexpected capacity: min %d max %d", aws.ToInt32(tg.MinCapacity), aws.ToInt32(tg.MaxCapacity))
+	}
+	if tg.ScalableDimension != aasTypes.ScalableDimensionECSServiceDesiredCount || tg.ServiceNamespace != aasTypes.ServiceNamespaceEcs {
+		t.Errorf("unexpected dimension or namespace: %s %s", tg.ScalableDimension, tg.ServiceNamespace)
+	}
+	if tg.SuspendedState == nil || aws.ToBool(tg.SuspendedState.DynamicScalingInSuspended) {
+		t.Errorf("unexpected suspended state: %#v", tg.SuspendedState)
+	}
+
+	if len(policies) != 1 {
+		t.Fatalf("unexpected number of scaling policies: %d", len(policies))
+	}
+	p := policies[0]
+	if aws.ToString(p.PolicyName) != "cpu" || p.PolicyType != aasTypes.PolicyTypeTargetTrackingScaling {
+		t.Errorf("unexpected policy: %s %s", aws.ToString(p.PolicyName), p.PolicyType)
+	}
+	if aws.ToString(p.ResourceId) != resourceId {
+		t.Errorf("unexpected resource id: %s", aws.ToString(p.ResourceId))
+	}
+	c := p.TargetTrackingScalingPolicyConfiguration
+	if c == nil || aws.ToFloat64(c.TargetValue) != 50 || aws.ToInt32(c.ScaleInCooldown) != 300 {
+		t.Errorf("unexpected target tracking configuration: %#v", c)
+	}
+	if c.PredefinedMetricSpecification.PredefinedMetricType != aasTypes.MetricTypeECSServiceAverageCPUUtilization {
+		t.Errorf("unexpected metric type: %s", c.PredefinedMetricSpecification.PredefinedMetricType)
+	}
+}
@@ -0,0 +1,84 @@
+package ecspresso
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/samber/lo"
+)
+
+// ConfigTags represents a tag policy for the service, task definitions and tasks.
+type ConfigTags struct {
+	Default       map[string]string   `yaml:"default,omitempty" json:"default,omitempty"`
+	Required      []string            `yaml:"required,omitempty" json:"required,omitempty"`
+	AllowedValues map[string][]string `yaml:"allowed_values,omitempty" json:"allowed_values,omitempty"`
+}
+
+// applyDefaults returns tags with the default tags which are not defined in tags.
+func (c *ConfigTags) applyDefaults(tags []types.Tag) []types.Tag {
+	if c == nil || len(c.Default) == 0 {
+		return tags
+	}
+	keys := lo.Keys(c.Default)
+	sort.Strings(keys)
+	for _, k := range keys {
+		k := k
+		if lo.ContainsBy(tags, func(t types.Tag) bool { return aws.ToString(t.Key) == k }) {
+			continue
+		}
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(c.Default[k])})
+	}
+	return tags
+}
+
+func (c *ConfigTags) Apply(v hasTags) error {
+	v.SetTags(c.applyDefaults(v.GetTags()))
+	return nil
+}
+
+// check returns an error when tags violate the tag policy.
+func (c *ConfigTags) check(tags []types.Tag) error {
+	if c == nil {
+		return nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	var problems []string
+	var missing []string
+	for _, k := range c.Required {
+		if _, ok := m[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("missing required tags: %s", strings.Join(missing, ",")))
+	}
+	keys := lo.Keys(c.AllowedValues)
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, ok := m[k]
+		if !ok {
+			continue
+		}
+		if allowed := c.AllowedValues[k]; !lo.Contains(allowed, v) {
+			problems = append(problems, fmt.Sprintf("tag %s=%s is not allowed (allowed values: %s)", k, v, strings.Join(allowed, ",")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// checkTagPolicy checks tags of the resource by the tag policy in the config.
+func (d *App) checkTagPolicy(resource string, tags []types.Tag) error {
+	if err := d.config.Tags.check(tags); err != nil {
+		return fmt.Errorf("tags of the %s violate the tag policy: %w", resource, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package ecspresso_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func tagsToMap(tags []types.Tag) map[string]string {
+	m := map[string]string{}
+	for _, t := range tags {
+		m[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	return m
+}
+
+func TestDefaultTags(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/tags/ecspresso.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"Name": "test", // not overwritten by the default
+		"Team": "platform",
+	}
+	td, err := app.LoadTaskDefinition(app.Config().TaskDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, tagsToMap(td.Tags)); diff != "" {
+		t.Errorf("unexpected task definition tags: %s", diff)
+	}
+	sv, err := app.LoadServiceDefinition(app.Config().ServiceDefinitionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, tagsToMap(sv.Tags)); diff != "" {
+		t.Errorf("unexpected service tags: %s", diff)
+	}
+
+	err = app.Register(ctx, ecspresso.RegisterOption{DryRun: true})
+	if err == nil || !strings.Contains(err.Error(), "missing required tags: Env") {
+		t.Errorf("expected tag policy error, got %v", err)
+	}
+}
+
+func TestTagPolicy(t *testing.T) {
+	policy := &ecspresso.ConfigTags{
+		Required: []string{"Team", "Env"},
+		AllowedValues: map[string][]string{
+			"Env": {"prod", "staging"},
+		},
+	}
+	tag := func(k, v string) types.Tag {
+		return types.Tag{Key: aws.String(k), Value: aws.String(v)}
+	}
+	tests := []struct {
+		tags []types.Tag
+		err  string
+	}{
+		{
+			tags: []types.Tag{tag("Team", "a"), tag("Env", "prod")},
+		},
+		{
+			tags: []types.Tag{tag("Env", "staging")},
+			err:  "missing required tags: Team",
+		},
+		{
+			tags: nil,
+			err:  "missing required tags: Team,Env",
+		},
+		{
+			tags: []types.Tag{tag("Team", "a"), tag("Env", "dev")},
+			err:  "tag Env=dev is not allowed (allowed values: prod,staging)",
+		},
+	}
+	for _, tt := range tests {
+		err := policy.Check(tt.tags)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("expected error %q, got %v", tt.err, err)
+		}
+	}
+	var nilPolicy *ecspresso.ConfigTags
+	if err := nilPolicy.Check(nil); err != nil {
+		t.Errorf("nil policy must not return error: %s", err)
+	}
+}
@@ -0,0 +1,349 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const taskSetExternalID = "ecspresso"
+
+// ConfigTaskSet represents the deployment by task sets for services with the EXTERNAL deployment controller.
+type ConfigTaskSet struct {
+	ScaleSteps   []float64 `yaml:"scale_steps,omitempty" json:"scale_steps,omitempty"`
+	StepInterval *Duration `yaml:"step_interval,omitempty" json:"step_interval,omitempty"`
+}
+
+func (c *ConfigTaskSet) setup() error {
+	if c == nil {
+		return nil
+	}
+	var prev float64
+	for _, s := range c.ScaleSteps {
+		if s <= prev || s > 100 {
+			return fmt.Errorf("task_set: scale_steps must be increasing percentages in (0, 100]: %v", c.ScaleSteps)
+		}
+		prev = s
+	}
+	if prev != 100 {
+		// the new task set is scaled to 100% before promoted to primary
+		c.ScaleSteps = append(c.ScaleSteps, 100)
+	}
+	if c.StepInterval == nil {
+		c.StepInterval = &Duration{}
+	}
+	return nil
+}
+
+func (c *ConfigTaskSet) scaleSteps() []float64 {
+	if c == nil {
+		return []float64{100}
+	}
+	return c.ScaleSteps
+}
+
+func (c *ConfigTaskSet) stepInterval() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.StepInterval.Duration
+}
+
+func (sv *Service) isExternal() bool {
+	return sv.DeploymentController != nil && sv.DeploymentController.Type == types.DeploymentControllerTypeExternal
+}
+
+func (sv *Service) primaryTaskSet() (types.TaskSet, bool) {
+	for _, ts := range sv.TaskSets {
+		if aws.ToString(ts.Status) == "PRIMARY" {
+			return ts, true
+		}
+	}
+	return types.TaskSet{}, false
+}
+
+// newCreateTaskSetInput returns an input of CreateTaskSet by the service definition.
+// Attributes not defined in the service definition are taken from the primary task set.
+func (d *App) newCreateTaskSetInput(sv *Service, primary *types.TaskSet, taskDefinitionArn string) *ecs.CreateTaskSetInput {
+	in := &ecs.CreateTaskSetInput{
+		Cluster:                  aws.String(d.Cluster),
+		Service:                  aws.String(d.Service),
+		TaskDefinition:           aws.String(taskDefinitionArn),
+		ExternalId:               aws.String(taskSetExternalID),
+		CapacityProviderStrategy: sv.CapacityProviderStrategy,
+		LaunchType:               sv.LaunchType,
+		LoadBalancers:            sv.LoadBalancers,
+		NetworkConfiguration:     sv.NetworkConfiguration,
+		PlatformVersion:          sv.PlatformVersion,
+		ServiceRegistries:        sv.ServiceRegistries,
+	}
+	if primary == nil {
+		return in
+	}
+	if len(in.CapacityProviderStrategy) == 0 && in.LaunchType == "" {
+		in.CapacityProviderStrategy = primary.CapacityProviderStrategy
+		in.LaunchType = primary.LaunchType
+	}
+	if len(in.LoadBalancers) == 0 {
+		in.LoadBalancers = primary.LoadBalancers
+	}
+	if in.NetworkConfiguration == nil {
+		in.NetworkConfiguration = primary.NetworkConfiguration
+	}
+	if in.PlatformVersion == nil {
+		in.PlatformVersion = primary.PlatformVersion
+	}
+	if len(in.ServiceRegistries) == 0 {
+		in.ServiceRegistries = primary.ServiceRegistries
+	}
+	return in
+}
+
+// DeployByTaskSet deploys the service with the EXTERNAL deployment controller.
+// It creates a new task set, scales it up step by step, promotes it to primary and deletes the other task sets.
+func (d *App) DeployByTaskSet(ctx context.Context, taskDefinitionArn string, count *int32, sv *Service, opt DeployOption) error {
+	if count != nil {
+		d.Log("updating desired count to %d", *count)
+		if _, err := d.ecs.UpdateService(ctx, &ecs.UpdateServiceInput{
+			Service:      aws.String(d.Service),
+			Cluster:      aws.String(d.Cluster),
+			DesiredCount: count,
+		}); err != nil {
+			return fmt.Errorf("failed to update service: %w", err)
+		}
+	}
+	if opt.SkipTaskDefinition && !opt.UpdateService && !opt.ForceNewDeployment {
+		// no need to create a new task set.
+		return nil
+	}
+
+	current, err := d.DescribeService(ctx)
+	if err != nil {
+		return err
+	}
+	var primary *types.TaskSet
+	if ts, ok := current.primaryTaskSet(); ok {
+		primary = &ts
+	}
+	in := d.newCreateTaskSetInput(sv, primary, taskDefinitionArn)
+	return d.shiftTaskSet(ctx, in, current.TaskSets, opt.Wait)
+}
+
+// shiftTaskSet creates a new task set by in and shifts the service to it.
+// When wait is true, the task set is scaled along the scale steps, waiting for it steady and watching the alarms.
+// Otherwise, it is scaled to 100% at once.
+// When it fails before the new task set is promoted to primary, the new task set is deleted.
+func (d *App) shiftTaskSet(ctx context.Context, in *ecs.CreateTaskSetInput, olds []types.TaskSet, wait bool) (err error) {
+	steps := d.config.TaskSet.scaleSteps()
+	if !wait {
+		// scale to 100% at once
+		steps = steps[len(steps)-1:]
+	}
+	in.Scale = &types.Scale{Unit: types.ScaleUnitPercent, Value: steps[0]}
+	d.Log("Creating a new task set with %s scale %.1f%%", arnToName(aws.ToString(in.TaskDefinition)), steps[0])
+	out, err := d.ecs.CreateTaskSet(ctx, in)
+	if err != nil {
+		return fmt.Errorf("failed to create task set: %w", err)
+	}
+	tsArn := aws.ToString(out.TaskSet.TaskSetArn)
+	d.Log("Task set %s is created", aws.ToString(out.TaskSet.Id))
+
+	promoted := false
+	defer func() {
+		if err != nil && !promoted {
+			d.discardTaskSet(ctx, tsArn)
+		}
+	}()
+
+	stepCtx := ctx
+	if wait && d.config.AlarmWatch != nil {
+		// the alarms are watched during the traffic shift
+		var cancel context.CancelCauseFunc
+		stepCtx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		d.Log("Watching alarms %s during scaling the task set", strings.Join(d.config.AlarmWatch.AlarmNames, ","))
+		go func() {
+			if err := d.watchAlarms(stepCtx); err != nil {
+				cancel(err)
+			}
+		}()
+	}
+	if err := d.scaleTaskSetSteps(stepCtx, tsArn, steps, wait); err != nil {
+		var errAlarm ErrAlarm
+		if cause := context.Cause(stepCtx); errors.As(cause, &errAlarm) {
+			return cause
+		}
+		return err
+	}
+
+	if err := d.promoteTaskSet(ctx, tsArn); err != nil {
+		return err
+	}
+	promoted = true
+	return d.deleteTaskSets(ctx, olds, tsArn)
+}
+
+// scaleTaskSetSteps scales the task set along the steps.
+func (d *App) scaleTaskSetSteps(ctx context.Context, tsArn string, steps []float64, wait bool) error {
+	for i, step := range steps {
+		if i > 0 {
+			if err := d.scaleTaskSet(ctx, tsArn, step); err != nil {
+				return err
+			}
+		}
+		if !wait {
+			break
+		}
+		if err := d.waitTaskSetSteady(ctx, tsArn); err != nil {
+			return err
+		}
+		if i < len(steps)-1 && d.config.TaskSet.stepInterval() > 0 {
+			d.Log("Waiting %s for the next step", d.config.TaskSet.stepInterval())
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d.config.TaskSet.stepInterval()):
+			}
+		}
+	}
+	return nil
+}
+
+// discardTaskSet deletes the task set created by the failed deployment.
+// The primary task set is kept, so the service is rolled back.
+func (d *App) discardTaskSet(ctx context.Context, tsArn string) {
+	// ctx may be already canceled by timeout
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+	d.Log("[WARNING] Deleting the task set %s created by the failed deployment", arnToName(tsArn))
+	if _, err := d.ecs.DeleteTaskSet(ctx, &ecs.DeleteTaskSetInput{
+		Cluster: aws.String(d.Cluster),
+		Service: aws.String(d.Service),
+		TaskSet: aws.String(tsArn),
+		Force:   aws.Bool(true),
+	}); err != nil {
+		d.Log("[WARNING] failed to delete task set %s: %s", arnToName(tsArn), err)
+	}
+}
+
+func (d *App) scaleTaskSet(ctx context.Context, tsArn string, percent float64) error {
+	d.Log("Scaling task set %s to %.1f%%", arnToName(tsArn), percent)
+	if _, err := d.ecs.UpdateTaskSet(ctx, &ecs.UpdateTaskSetInput{
+		Cluster: aws.String(d.Cluster),
+		Service: aws.String(d.Service),
+		TaskSet: aws.String(tsArn),
+		Scale:   &types.Scale{Unit: types.ScaleUnitPercent, Value: percent},
+	}); err != nil {
+		return fmt.Errorf("failed to update task set: %w", err)
+	}
+	return nil
+}
+
+func (d *App) promoteTaskSet(ctx context.Context, tsArn string) error {
+	d.Log("Promoting task set %s to primary", arnToName(tsArn))
+	if _, err := d.ecs.UpdateServicePrimaryTaskSet(ctx, &ecs.UpdateServicePrimaryTaskSetInput{
+		Cluster:        aws.String(d.Cluster),
+		Service:        aws.String(d.Service),
+		PrimaryTaskSet: aws.String(tsArn),
+	}); err != nil {
+		return fmt.Errorf("failed to update primary task set: %w", err)
+	}
+	return nil
+}
+
+// deleteTaskSets deletes the task sets except keepArn.
+func (d *App) deleteTaskSets(ctx context.Context, tss []types.TaskSet, keepArn string) error {
+	for _, ts := range tss {
+		if aws.ToString(ts.TaskSetArn) == keepArn {
+			continue
+		}
+		d.Log("Deleting task set %s (%s)", aws.ToString(ts.Id), arnToName(aws.ToString(ts.TaskDefinition)))
+		if _, err := d.ecs.DeleteTaskSet(ctx, &ecs.DeleteTaskSetInput{
+			Cluster: aws.String(d.Cluster),
+			Service: aws.String(d.Service),
+			TaskSet: ts.TaskSetArn,
+		}); err != nil {
+			return fmt.Errorf("failed to delete task set %s: %w", aws.ToString(ts.Id), err)
+		}
+	}
+	return nil
+}
+
+// waitTaskSetSteady waits for the task set to reach STEADY_STATE.
+func (d *App) waitTaskSetSteady(ctx context.Context, tsArn string) error {
+	var prev string
+	for {
+		out, err := d.ecs.DescribeTaskSets(ctx, &ecs.DescribeTaskSetsInput{
+			Cluster:  aws.String(d.Cluster),
+			Service:  aws.String(d.Service),
+			TaskSets: []string{tsArn},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe task set: %w", err)
+		}
+		if len(out.TaskSets) == 0 {
+			return ErrNotFound(fmt.Sprintf("task set %s is not found", tsArn))
+		}
+		ts := out.TaskSets[0]
+		if s := formatTaskSet(ts); s != prev {
+			d.Log(s)
+			prev = s
+		}
+		if ts.StabilityStatus == types.StabilityStatusSteadyState {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for task set steady: %w", ctx.Err())
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// RollbackByTaskSet rolls back the service with the EXTERNAL deployment controller.
+// When task sets other than the primary exist (the deployment is in progress), they are deleted.
+// Otherwise, a task set of targetArn is promoted to primary.
+func (d *App) RollbackByTaskSet(ctx context.Context, _ *Service, targetArn string, opt RollbackOption) (string, error) {
+	// sv may be loaded from the service definition, so task sets are described from the service
+	sv, err := d.DescribeService(ctx)
+	if err != nil {
+		return "", err
+	}
+	primary, ok := sv.primaryTaskSet()
+	if !ok {
+		return "", ErrNotFound("primary task set is not found")
+	}
+	primaryArn := aws.ToString(primary.TaskSetArn)
+	if len(sv.TaskSets) > 1 {
+		var rollbackedTdArn string
+		for _, ts := range sv.TaskSets {
+			if aws.ToString(ts.TaskSetArn) != primaryArn {
+				rollbackedTdArn = aws.ToString(ts.TaskDefinition)
+			}
+		}
+		d.Log("the deployment in progress found, deleting task sets except the primary %s %s", aws.ToString(primary.Id), opt.DryRunString())
+		if opt.DryRun {
+			return rollbackedTdArn, nil
+		}
+		return rollbackedTdArn, d.deleteTaskSets(ctx, sv.TaskSets, primaryArn)
+	}
+
+	currentArn := aws.ToString(primary.TaskDefinition)
+	d.Log("Rolling back to %s %s", arnToName(targetArn), opt.DryRunString())
+	if opt.DryRun {
+		return currentArn, nil
+	}
+	in := d.newCreateTaskSetInput(&Service{}, &primary, targetArn)
+	// the task set is scaled to 100% at once without watching alarms, which may be still in ALARM state.
+	// the caller waits for the service stable.
+	if err := d.shiftTaskSet(ctx, in, sv.TaskSets, false); err != nil {
+		return "", err
+	}
+	return currentArn, nil
+}
@@ -0,0 +1,229 @@
+package ecspresso_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+const (
+	testTaskSetTdArn1 = "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:1"
+	testTaskSetTdArn2 = "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/test:2"
+	testNewTaskSetArn = "arn:aws:ecs:ap-northeast-1:123456789012:task-set/default/test/ecs-svc/new"
+)
+
+func testTaskSet(id, tdArn, status string) types.TaskSet {
+	return types.TaskSet{
+		Id:              ptr(id),
+		TaskSetArn:      ptr("arn:aws:ecs:ap-northeast-1:123456789012:task-set/default/test/" + id),
+		TaskDefinition:  ptr(tdArn),
+		Status:          ptr(status),
+		StabilityStatus: types.StabilityStatusSteadyState,
+		LaunchType:      types.LaunchTypeFargate,
+	}
+}
+
+// newTaskSetTestApp returns an App which has an EXTERNAL deployment controller service with taskSets.
+func newTaskSetTestApp(t *testing.T, taskSets []types.TaskSet) (*ecspresso.App, *sdkMock) {
+	return newSDKMockApp(t, "tests/taskset/ecspresso.yml", taskSetResults(taskSets))
+}
+
+func taskSetResults(taskSets []types.TaskSet) map[string]any {
+	ts := testTaskSet("new", testTaskSetTdArn2, "ACTIVE")
+	return map[string]any{
+		"DescribeServices": &ecs.DescribeServicesOutput{
+			Services: []types.Service{
+				{
+					ServiceName:          ptr("test"),
+					Status:               ptr("ACTIVE"),
+					DeploymentController: &types.DeploymentController{Type: types.DeploymentControllerTypeExternal},
+					TaskSets:             taskSets,
+				},
+			},
+		},
+		"CreateTaskSet":               &ecs.CreateTaskSetOutput{TaskSet: &types.TaskSet{Id: ptr("new"), TaskSetArn: ptr(testNewTaskSetArn)}},
+		"DescribeTaskSets":            &ecs.DescribeTaskSetsOutput{TaskSets: []types.TaskSet{ts}},
+		"UpdateTaskSet":               &ecs.UpdateTaskSetOutput{},
+		"UpdateServicePrimaryTaskSet": &ecs.UpdateServicePrimaryTaskSetOutput{},
+		"DeleteTaskSet":               &ecs.DeleteTaskSetOutput{},
+	}
+}
+
+// taskSetCalls returns the called operations which modify task sets.
+func taskSetCalls(m *sdkMock) []sdkCall {
+	return m.ops("DescribeServices", "DescribeTaskSets")
+}
+
+func callOps(calls []sdkCall) []string {
+	var ops []string
+	for _, c := range calls {
+		ops = append(ops, c.op)
+	}
+	return ops
+}
+
+func TestConfigTaskSet(t *testing.T) {
+	app, _ := newTaskSetTestApp(t, nil)
+	steps := app.Config().TaskSet.ScaleSteps_()
+	if len(steps) != 2 || steps[0] != 25 || steps[1] != 100 {
+		t.Errorf("unexpected scale steps %v", steps)
+	}
+	if _, err := ecspresso.New(context.Background(), &ecspresso.CLIOptions{ConfigFilePath: "tests/taskset/invalid.yml"}); err == nil {
+		t.Error("expected error for decreasing scale_steps")
+	}
+}
+
+func TestDeployByTaskSet(t *testing.T) {
+	old := testTaskSet("old", testTaskSetTdArn1, "PRIMARY")
+	app, m := newTaskSetTestApp(t, []types.TaskSet{old})
+	sv := &ecspresso.Service{}
+	if err := app.DeployByTaskSet(context.Background(), testTaskSetTdArn2, nil, sv, ecspresso.DeployOption{Wait: true, UpdateService: true}); err != nil {
+		t.Fatal(err)
+	}
+	calls := taskSetCalls(m)
+	ops := callOps(calls)
+	expected := []string{"CreateTaskSet", "UpdateTaskSet", "UpdateServicePrimaryTaskSet", "DeleteTaskSet"}
+	if len(ops) != len(expected) {
+		t.Fatalf("unexpected operations %v", ops)
+	}
+	for i := range expected {
+		if ops[i] != expected[i] {
+			t.Fatalf("unexpected operations %v", ops)
+		}
+	}
+	create := calls[0].in.(*ecs.CreateTaskSetInput)
+	if *create.TaskDefinition != testTaskSetTdArn2 || create.Scale.Value != 25 || create.LaunchType != types.LaunchTypeFargate {
+		t.Errorf("unexpected CreateTaskSet input %#v", create)
+	}
+	if v := calls[1].in.(*ecs.UpdateTaskSetInput).Scale.Value; v != 100 {
+		t.Errorf("unexpected scale %f", v)
+	}
+	if v := *calls[2].in.(*ecs.UpdateServicePrimaryTaskSetInput).PrimaryTaskSet; v != testNewTaskSetArn {
+		t.Errorf("unexpected primary task set %s", v)
+	}
+	if v := *calls[3].in.(*ecs.DeleteTaskSetInput).TaskSet; v != *old.TaskSetArn {
+		t.Errorf("unexpected deleted task set %s", v)
+	}
+}
+
+func TestDeployByTaskSetFailure(t *testing.T) {
+	old := testTaskSet("old", testTaskSetTdArn1, "PRIMARY")
+	t.Run("not steady", func(t *testing.T) {
+		results := taskSetResults([]types.TaskSet{old})
+		results["DescribeTaskSets"] = errors.New("throttled")
+		app, m := newSDKMockApp(t, "tests/taskset/ecspresso.yml", results)
+		if err := app.DeployByTaskSet(context.Background(), testTaskSetTdArn2, nil, &ecspresso.Service{}, ecspresso.DeployOption{Wait: true, UpdateService: true}); err == nil {
+			t.Fatal("expected error")
+		}
+		assertTaskSetDiscarded(t, m)
+	})
+	t.Run("alarm", func(t *testing.T) {
+		results := taskSetResults([]types.TaskSet{old})
+		// the new task set never becomes steady, and the alarm fires during scaling
+		stabilizing := testTaskSet("new", testTaskSetTdArn2, "ACTIVE")
+		stabilizing.StabilityStatus = types.StabilityStatusStabilizing
+		results["DescribeTaskSets"] = &ecs.DescribeTaskSetsOutput{TaskSets: []types.TaskSet{stabilizing}}
+		results["DescribeAlarms"] = &cloudwatch.DescribeAlarmsOutput{
+			MetricAlarms: []cwTypes.MetricAlarm{{AlarmName: ptr("test-5xx"), StateValue: cwTypes.StateValueAlarm}},
+		}
+		app, m := newSDKMockApp(t, "tests/taskset/alarm.yml", results)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := app.DeployByTaskSet(ctx, testTaskSetTdArn2, nil, &ecspresso.Service{}, ecspresso.DeployOption{Wait: true, UpdateService: true})
+		var errAlarm ecspresso.ErrAlarm
+		if !errors.As(err, &errAlarm) {
+			t.Fatalf("expected ErrAlarm, got %v", err)
+		}
+		assertTaskSetDiscarded(t, m)
+	})
+}
+
+// assertTaskSetDiscarded asserts that the new task set is deleted without promoted.
+func assertTaskSetDiscarded(t *testing.T, m *sdkMock) {
+	t.Helper()
+	if _, ok := m.called("UpdateServicePrimaryTaskSet"); ok {
+		t.Error("the new task set must not be promoted")
+	}
+	v, ok := m.called("DeleteTaskSet")
+	if !ok {
+		t.Fatal("the new task set is not deleted")
+	}
+	if in := v.(*ecs.DeleteTaskSetInput); *in.TaskSet != testNewTaskSetArn || !*in.Force {
+		t.Errorf("unexpected DeleteTaskSet input %#v", in)
+	}
+}
+
+func TestRollbackByTaskSet(t *testing.T) {
+	ctx := context.Background()
+	t.Run("in progress", func(t *testing.T) {
+		primary := testTaskSet("old", testTaskSetTdArn1, "PRIMARY")
+		active := testTaskSet("new", testTaskSetTdArn2, "ACTIVE")
+		app, m := newTaskSetTestApp(t, []types.TaskSet{primary, active})
+		tdArn, err := app.RollbackByTaskSet(ctx, &ecspresso.Service{}, testTaskSetTdArn1, ecspresso.RollbackOption{Wait: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tdArn != testTaskSetTdArn2 {
+			t.Errorf("unexpected rolled-back task definition %s", tdArn)
+		}
+		calls := taskSetCalls(m)
+		if len(calls) != 1 || calls[0].op != "DeleteTaskSet" || *calls[0].in.(*ecs.DeleteTaskSetInput).TaskSet != *active.TaskSetArn {
+			t.Errorf("unexpected operations %v", callOps(calls))
+		}
+	})
+	t.Run("completed", func(t *testing.T) {
+		primary := testTaskSet("current", testTaskSetTdArn2, "PRIMARY")
+		app, m := newTaskSetTestApp(t, []types.TaskSet{primary})
+		tdArn, err := app.RollbackByTaskSet(ctx, &ecspresso.Service{}, testTaskSetTdArn1, ecspresso.RollbackOption{Wait: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tdArn != testTaskSetTdArn2 {
+			t.Errorf("unexpected rolled-back task definition %s", tdArn)
+		}
+		assertTaskSetRolledBack(t, m, primary)
+		// rollback waits for the service stable after shifting
+		if n := m.count("DescribeTaskSets"); n != 0 {
+			t.Errorf("the task set must not be waited at each step: %d calls", n)
+		}
+	})
+	t.Run("alarm already firing", func(t *testing.T) {
+		primary := testTaskSet("current", testTaskSetTdArn2, "PRIMARY")
+		results := taskSetResults([]types.TaskSet{primary})
+		results["DescribeAlarms"] = &cloudwatch.DescribeAlarmsOutput{
+			MetricAlarms: []cwTypes.MetricAlarm{{AlarmName: ptr("test-5xx"), StateValue: cwTypes.StateValueAlarm}},
+		}
+		app, m := newSDKMockApp(t, "tests/taskset/alarm.yml", results)
+		if _, err := app.RollbackByTaskSet(ctx, &ecspresso.Service{}, testTaskSetTdArn1, ecspresso.RollbackOption{Wait: true}); err != nil {
+			t.Fatal(err)
+		}
+		assertTaskSetRolledBack(t, m, primary)
+		if n := m.count("DescribeAlarms"); n != 0 {
+			t.Errorf("alarms must not be watched during rollback: %d calls", n)
+		}
+	})
+}
+
+// assertTaskSetRolledBack asserts that the task set of the rollback target is promoted at once and old is deleted.
+func assertTaskSetRolledBack(t *testing.T, m *sdkMock, old types.TaskSet) {
+	t.Helper()
+	calls := taskSetCalls(m)
+	ops := callOps(calls)
+	if len(ops) != 3 || ops[0] != "CreateTaskSet" || ops[1] != "UpdateServicePrimaryTaskSet" || ops[2] != "DeleteTaskSet" {
+		t.Fatalf("unexpected operations %v", ops)
+	}
+	create := calls[0].in.(*ecs.CreateTaskSetInput)
+	if *create.TaskDefinition != testTaskSetTdArn1 || create.Scale.Value != 100 {
+		t.Errorf("unexpected CreateTaskSet input %#v", create)
+	}
+	if v := *calls[2].in.(*ecs.DeleteTaskSetInput).TaskSet; v != *old.TaskSetArn {
+		t.Errorf("unexpected deleted task set %s", v)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -16,6 +19,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	cloudwatchlogsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
@@ -35,9 +39,13 @@ type verifier struct {
 	secretsmanager *secretsmanager.Client
 	ecr            map[string]*ecr.Client
 	s3             *s3.Client
+	ec2            *ec2.Client
 	opt            *VerifyOption
 	isAssumed      bool
 	execCfg        *aws.Config
+	td             *TaskDefinitionInput
+	sv             *Service
+	mu             sync.Mutex
 }
 
 func (v *verifier) IsAssumed() bool {
@@ -53,6 +61,7 @@ func newVerifier(execCfg, appCfg *aws.Config, opt *VerifyOption) *verifier {
 			execCfg.Region: ecr.NewFromConfig(*execCfg),
 		},
 		s3:        s3.NewFromConfig(*appCfg),
+		ec2:       ec2.NewFromConfig(*appCfg),
 		opt:       opt,
 		isAssumed: execCfg != appCfg,
 		execCfg:   execCfg,
@@ -60,6 +69,8 @@ func newVerifier(execCfg, appCfg *aws.Config, opt *VerifyOption) *verifier {
 }
 
 func (v *verifier) ecrClient(region string) *ecr.Client {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 	if c, ok := v.ecr[region]; ok {
 		return c
 	}
@@ -182,101 +193,219 @@ func (d *App) newAssumedVerifier(ctx context.Context, cfg aws.Config, executionR
 
 // VerifyOption represents options for Verify()
 type VerifyOption struct {
-	GetSecrets bool `help:"get secrets from ParameterStore or SecretsManager" default:"true" negatable:""`
-	PutLogs    bool `help:"put logs to CloudWatchLogs" default:"true" negatable:""`
-	Cache      bool `help:"use cache" default:"true" negatable:""`
+	GetSecrets     bool   `help:"get secrets from ParameterStore or SecretsManager" default:"true" negatable:""`
+	PutLogs        bool   `help:"put logs to CloudWatchLogs" default:"true" negatable:""`
+	Cache          bool   `help:"use cache" default:"true" negatable:""`
+	SimulatePolicy bool   `help:"simulate IAM policies of the execution role and the task role" default:"true" negatable:""`
+	Output         string `help:"output format (text, json, junit)" default:"text" enum:"text,json,junit"`
+	Parallel       int    `help:"number of resources to verify in parallel" default:"4"`
 }
 
 type verifyResourceFunc func(context.Context) error
 
+type verifyTarget struct {
+	name string
+	fn   verifyResourceFunc
+}
+
 // Verify verifies service / task definitions related resources are valid.
 func (d *App) Verify(ctx context.Context, opt VerifyOption) error {
 	initVerifyState(opt.Cache)
+	verifyState.quiet = opt.Output != "" && opt.Output != "text"
+	if opt.Parallel > 1 {
+		verifyState.sem = make(chan struct{}, opt.Parallel-1)
+	}
 
 	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
 	if err != nil {
 		return err
 	}
+	var sv *Service
+	if d.config.ServiceDefinitionPath != "" {
+		if sv, err = d.LoadServiceDefinition(d.config.ServiceDefinitionPath); err != nil {
+			return err
+		}
+	}
 	d.verifier, err = d.newAssumedVerifier(ctx, d.config.awsv2Config, td.ExecutionRoleArn, &opt)
 	if err != nil {
 		return err
 	}
+	// definitions are loaded once because the config loader is not goroutine-safe
+	d.verifier.td = td
+	d.verifier.sv = sv
 
 	ctx, cancel := d.Start(ctx)
 	defer cancel()
 
 	d.Log("Starting verify")
-	resources := []struct {
-		name string
-		fn   verifyResourceFunc
-	}{
+	targets := []verifyTarget{
 		{name: "TaskDefinition", fn: d.verifyTaskDefinition},
 		{name: "ServiceDefinition", fn: d.verifyServiceDefinition},
 		{name: "Cluster", fn: d.verifyCluster},
 	}
-	for _, r := range resources {
-		if err := verifyResource(ctx, r.name, r.fn); err != nil {
+	if d.config.AppSpec != nil && len(d.config.AppSpec.Hooks) > 0 {
+		targets = append(targets, verifyTarget{name: "AppSpec", fn: d.verifyAppSpec})
+	}
+	errs := verifyResources(ctx, targets)
+
+	report := newVerifyReport(verifyState.root)
+	switch opt.Output {
+	case "json":
+		if err := report.OutputJSON(os.Stdout); err != nil {
 			return err
 		}
+	case "junit":
+		if err := report.OutputJUnit(os.Stdout); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		s := report.Summary
+		return fmt.Errorf("verify failed: %d NG, %d SKIP, %d OK", s.NG, s.Skip, s.OK)
 	}
 	d.Log("Verify OK!")
 	return nil
 }
 
 var verifyState = struct {
-	cache verifyCache
-	level int
+	cache *verifyCache
+	root  *VerifyResult
+	quiet bool
+	sem   chan struct{}
+	out   sync.Mutex
 }{
 	cache: nil,
-	level: 0,
+	root:  newVerifyResultRoot(),
 }
 
 func initVerifyState(cache bool) {
 	if cache {
-		verifyState.cache = make(verifyCache, 100)
+		verifyState.cache = newVerifyCache()
 	} else {
-		verifyState.cache = verifyCache(nil)
+		verifyState.cache = nil
 	}
-	verifyState.level = 0
+	verifyState.root = newVerifyResultRoot()
+	verifyState.quiet = false
+	verifyState.sem = nil
+}
+
+// verifyCache is a goroutine-safe cache of verify results.
+// Concurrent calls for the same name wait for the first one to complete.
+type verifyCache struct {
+	mu      sync.Mutex
+	entries map[string]*verifyCacheEntry
 }
 
-type verifyCache map[string]error
+type verifyCacheEntry struct {
+	done chan struct{}
+	err  error
+}
+
+func newVerifyCache() *verifyCache {
+	return &verifyCache{entries: make(map[string]*verifyCacheEntry, 100)}
+}
 
-func (v verifyCache) Do(ctx context.Context, name string, fn verifyResourceFunc) (error, bool) {
+func (v *verifyCache) Do(ctx context.Context, name string, fn verifyResourceFunc) (error, bool) {
 	if v == nil {
 		return fn(ctx), false
 	}
-	if err, ok := v[name]; ok {
-		return err, true
+	v.mu.Lock()
+	if e, ok := v.entries[name]; ok {
+		v.mu.Unlock()
+		<-e.done
+		return e.err, true
 	}
-	err := fn(ctx)
-	v[name] = err
-	return err, false
+	e := &verifyCacheEntry{done: make(chan struct{})}
+	v.entries[name] = e
+	v.mu.Unlock()
+
+	e.err = fn(ctx)
+	close(e.done)
+	return e.err, false
+}
+
+// verifyResources verifies the targets concurrently and returns errors of failed targets.
+// The number of goroutines is bounded by verifyState.sem. When no worker is available,
+// the target is verified in the caller's goroutine, so nested calls never deadlock.
+func verifyResources(ctx context.Context, targets []verifyTarget) verifyErrors {
+	parent := verifyResultFromContext(ctx)
+	results := make([]*VerifyResult, len(targets))
+	for i, t := range targets {
+		results[i] = parent.add(t.name)
+	}
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		i, t := i, t
+		select {
+		case verifyState.sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-verifyState.sem }()
+				errs[i] = runVerify(ctx, results[i], t.fn)
+			}()
+		default:
+			errs[i] = runVerify(ctx, results[i], t.fn)
+		}
+	}
+	wg.Wait()
+
+	var failed verifyErrors
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
 }
 
 func verifyResource(ctx context.Context, name string, verifyFunc func(context.Context) error) error {
-	verifyState.level++
-	defer func() { verifyState.level-- }()
-	indent := strings.Repeat("  ", verifyState.level)
-	print := func(f string, args ...interface{}) {
-		fmt.Printf(indent+f+"\n", args...)
+	return runVerify(ctx, verifyResultFromContext(ctx).add(name), verifyFunc)
+}
+
+func runVerify(ctx context.Context, result *VerifyResult, verifyFunc func(context.Context) error) error {
+	verifyErr, hit := verifyState.cache.Do(withVerifyResult(ctx, result), result.Name, verifyFunc)
+	result.Cached = hit
+	switch {
+	case verifyErr == nil:
+		result.Status = VerifyStatusOK
+	case errors.As(verifyErr, &errSkipVerify):
+		result.Status = VerifyStatusSkip
+		result.Message = verifyErr.Error()
+		verifyErr = nil
+	default:
+		result.Status = VerifyStatusNG
+		result.Message = verifyErr.Error()
+		verifyErr = fmt.Errorf("verify %s failed: %w", result.Name, verifyErr)
+	}
+	if result.level == 1 && !verifyState.quiet {
+		// print a top level resource with nested resources at once to keep the output grouped
+		verifyState.out.Lock()
+		printVerifyResult(os.Stdout, result)
+		verifyState.out.Unlock()
+	}
+	return verifyErr
+}
+
+func printVerifyResult(w io.Writer, r *VerifyResult) {
+	indent := strings.Repeat("  ", r.level)
+	fmt.Fprintf(w, "%s%s\n", indent, r.Name)
+	for _, c := range r.Resources {
+		printVerifyResult(w, c)
 	}
-	print("%s", name)
 	var cached string
-	verifyErr, hit := verifyState.cache.Do(ctx, name, verifyFunc)
-	if hit {
+	if r.Cached {
 		cached = color.CyanString("(cached)")
 	}
-	if verifyErr != nil {
-		if errors.As(verifyErr, &errSkipVerify) {
-			print("--> [%s]%s %s", color.CyanString("SKIP"), cached, color.CyanString(verifyErr.Error()))
-			return nil
-		}
-		print("--> [%s]%s %s", color.RedString("NG"), cached, color.RedString(verifyErr.Error()))
-		return fmt.Errorf("verify %s failed: %w", name, verifyErr)
+	switch r.Status {
+	case VerifyStatusOK:
+		fmt.Fprintf(w, "%s--> [%s]%s\n", indent, color.GreenString("OK"), cached)
+	case VerifyStatusSkip:
+		fmt.Fprintf(w, "%s--> [%s]%s %s\n", indent, color.CyanString("SKIP"), cached, color.CyanString(r.Message))
+	case VerifyStatusNG:
+		fmt.Fprintf(w, "%s--> [%s]%s %s\n", indent, color.RedString("NG"), cached, color.RedString(r.Message))
 	}
-	print("--> [%s]%s", color.GreenString("OK"), cached)
-	return nil
 }
 
 func (d *App) verifyCluster(ctx context.Context) error {
@@ -293,121 +422,159 @@ func (d *App) verifyCluster(ctx context.Context) error {
 }
 
 func (d *App) verifyServiceDefinition(ctx context.Context) error {
-	if d.config.ServiceDefinitionPath == "" {
+	sv, td := d.verifier.sv, d.verifier.td
+	if sv == nil {
 		return ErrSkipVerify("no ServiceDefinition")
 	}
-	sv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
-	if err != nil {
-		return err
-	}
-	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
-	if err != nil {
-		return err
-	}
 
+	var errs verifyErrors
 	// networkMode
 	if td.NetworkMode == types.NetworkModeAwsvpc {
 		if sv.NetworkConfiguration == nil || sv.NetworkConfiguration.AwsvpcConfiguration == nil {
-			return errors.New(
+			errs = append(errs, errors.New(
 				`networkConfiguration.awsvpcConfiguration required for the taskDefinition networkMode=awsvpc`,
-			)
+			))
+		}
+	}
+	if len(sv.LoadBalancers) == 0 && sv.HealthCheckGracePeriodSeconds != nil {
+		errs = append(errs, errors.New("service has no load balancers, but healthCheckGracePeriodSeconds is defined"))
+	}
+	if sv.isBlueGreen() {
+		for i, lb := range sv.LoadBalancers {
+			if lb.AdvancedConfiguration == nil {
+				errs = append(errs, fmt.Errorf("loadBalancers[%d].advancedConfiguration is required for the BLUE_GREEN deployment strategy", i))
+			}
 		}
 	}
 
+	var targets []verifyTarget
+	if nc := sv.NetworkConfiguration; nc != nil && nc.AwsvpcConfiguration != nil {
+		targets = append(targets, verifyTarget{
+			name: "NetworkConfiguration",
+			fn: func(ctx context.Context) error {
+				return d.verifyNetworkConfiguration(ctx, sv)
+			},
+		})
+	}
+	targets = append(targets, verifyTarget{
+		name: "Placement",
+		fn:   d.verifyPlacement,
+	})
 	// LB
 	for i, lb := range sv.LoadBalancers {
-		name := fmt.Sprintf("LoadBalancer[%d]", i)
-		err := verifyResource(ctx, name, func(context.Context) error {
-			out, err := d.elbv2.DescribeTargetGroups(ctx, &elasticloadbalancingv2.DescribeTargetGroupsInput{
-				TargetGroupArns: []string{*lb.TargetGroupArn},
+		lb := lb
+		targets = append(targets, verifyTarget{
+			name: fmt.Sprintf("LoadBalancer[%d]", i),
+			fn: func(ctx context.Context) error {
+				return d.verifyLoadBalancer(ctx, lb, td)
+			},
+		})
+	}
+	if dc := sv.DeploymentConfiguration; dc != nil {
+		for i, hook := range dc.LifecycleHooks {
+			hook := hook
+			targets = append(targets, verifyTarget{
+				name: fmt.Sprintf("LifecycleHooks[%d]", i),
+				fn: func(ctx context.Context) error {
+					return d.verifyLifecycleHook(ctx, hook)
+				},
 			})
-			if err != nil {
-				return err
-			} else if len(out.TargetGroups) == 0 {
-				return ErrNotFound(fmt.Sprintf("target group %s is not found: %s", *lb.TargetGroupArn, err))
-			}
-
-			cname := aws.ToString(lb.ContainerName)
-			cport := aws.ToInt32(lb.ContainerPort)
-			var container *types.ContainerDefinition
-		CONTAINER_DEF:
-			for _, c := range td.ContainerDefinitions {
-				if aws.ToString(c.Name) != cname {
-					continue
-				}
-				for _, pm := range c.PortMappings {
-					if aws.ToInt32(pm.ContainerPort) == cport {
-						container = &c
-						break CONTAINER_DEF
+		}
+	}
+	for i, vc := range sv.VolumeConfigurations {
+		vc := vc
+		name := fmt.Sprintf("VolumeConfigurations[%d]", i)
+		targets = append(targets, verifyTarget{
+			name: name,
+			fn: func(context.Context) error {
+				if ebs := vc.ManagedEBSVolume; ebs != nil {
+					if len(ebs.TagSpecifications) > 1 {
+						d.Log("[WARNING] %s has more than one tag specifications. Only the first tag specification is used.", name)
 					}
 				}
-			}
-			if container == nil {
-				return fmt.Errorf("container name %s and port %d is not defined in task definition", cname, cport)
-			}
-			return nil
+				return nil
+			},
 		})
-		if err != nil {
-			return err
-		}
 	}
-	if len(sv.LoadBalancers) == 0 && sv.HealthCheckGracePeriodSeconds != nil {
-		return errors.New("service has no load balancers, but healthCheckGracePeriodSeconds is defined")
+	errs = append(errs, verifyResources(ctx, targets)...)
+	return errs.orNil()
+}
+
+func (d *App) verifyLoadBalancer(ctx context.Context, lb types.LoadBalancer, td *TaskDefinitionInput) error {
+	out, err := d.elbv2.DescribeTargetGroups(ctx, &elasticloadbalancingv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []string{*lb.TargetGroupArn},
+	})
+	if err != nil {
+		return err
+	} else if len(out.TargetGroups) == 0 {
+		return ErrNotFound(fmt.Sprintf("target group %s is not found: %s", *lb.TargetGroupArn, err))
 	}
 
-	for i, vc := range sv.VolumeConfigurations {
-		name := fmt.Sprintf("VolumeConfigurations[%d]", i)
-		err := verifyResource(ctx, name, func(context.Context) error {
-			if ebs := vc.ManagedEBSVolume; ebs != nil {
-				if len(ebs.TagSpecifications) > 1 {
-					d.Log("[WARNING] %s has more than one tag specifications. Only the first tag specification is used.", name)
-				}
+	cname := aws.ToString(lb.ContainerName)
+	cport := aws.ToInt32(lb.ContainerPort)
+	var container *types.ContainerDefinition
+CONTAINER_DEF:
+	for _, c := range td.ContainerDefinitions {
+		if aws.ToString(c.Name) != cname {
+			continue
+		}
+		for _, pm := range c.PortMappings {
+			if aws.ToInt32(pm.ContainerPort) == cport {
+				container = &c
+				break CONTAINER_DEF
 			}
-			return nil
-		})
-		if err != nil {
-			return err
 		}
 	}
-
+	if container == nil {
+		return fmt.Errorf("container name %s and port %d is not defined in task definition", cname, cport)
+	}
 	return nil
 }
 
 func (d *App) verifyTaskDefinition(ctx context.Context) error {
-	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
-	if err != nil {
-		return err
-	}
+	td := d.verifier.td
 
+	var targets []verifyTarget
 	if execRole := td.ExecutionRoleArn; execRole != nil {
-		name := fmt.Sprintf("ExecutionRole[%s]", *execRole)
-		err := verifyResource(ctx, name, func(ctx context.Context) error {
-			return d.verifyRole(ctx, *execRole)
+		targets = append(targets, verifyTarget{
+			name: fmt.Sprintf("ExecutionRole[%s]", *execRole),
+			fn: func(ctx context.Context) error {
+				return d.verifyRole(ctx, *execRole)
+			},
+		})
+		targets = append(targets, verifyTarget{
+			name: fmt.Sprintf("ExecutionRolePermissions[%s]", *execRole),
+			fn: func(ctx context.Context) error {
+				return d.verifyExecutionRolePermissions(ctx, *execRole)
+			},
 		})
-		if err != nil {
-			return err
-		}
 	}
 	if taskRole := td.TaskRoleArn; taskRole != nil {
-		name := fmt.Sprintf("TaskRole[%s]", *taskRole)
-		err := verifyResource(ctx, name, func(ctx context.Context) error {
-			return d.verifyRole(ctx, *taskRole)
+		targets = append(targets, verifyTarget{
+			name: fmt.Sprintf("TaskRole[%s]", *taskRole),
+			fn: func(ctx context.Context) error {
+				return d.verifyRole(ctx, *taskRole)
+			},
 		})
-		if err != nil {
-			return err
+		if d.config.Verify != nil && len(d.config.Verify.TaskRolePermissions) > 0 {
+			targets = append(targets, verifyTarget{
+				name: fmt.Sprintf("TaskRolePermissions[%s]", *taskRole),
+				fn: func(ctx context.Context) error {
+					return d.verifyTaskRolePermissions(ctx, *taskRole)
+				},
+			})
 		}
 	}
-
 	for _, c := range td.ContainerDefinitions {
-		name := fmt.Sprintf("ContainerDefinition[%s]", aws.ToString(c.Name))
-		err := verifyResource(ctx, name, func(ctx context.Context) error {
-			return d.verifyContainer(ctx, &c, td)
+		c := c
+		targets = append(targets, verifyTarget{
+			name: fmt.Sprintf("ContainerDefinition[%s]", aws.ToString(c.Name)),
+			fn: func(ctx context.Context) error {
+				return d.verifyContainer(ctx, &c, td)
+			},
 		})
-		if err != nil {
-			return err
-		}
 	}
-	return nil
+	return verifyResources(ctx, targets).orNil()
 }
 
 var (
@@ -447,16 +614,10 @@ func (d *App) verifyRegistryImage(ctx context.Context, image, user, password str
 		return fmt.Errorf("%s:%s is not found in Registry", image, tag)
 	}
 
-	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
-	if err != nil {
-		return err
-	}
+	td := d.verifier.td
 	// when requiredCompatibilities contain only fargate, regard as fargate task definition
 	isFargateTask := len(td.RequiresCompatibilities) == 1 && td.RequiresCompatibilities[0] == types.CompatibilityFargate
-	isFargateService, err := d.isFargateService()
-	if err != nil {
-		return err
-	}
+	isFargateService := isFargateService(d.verifier.sv)
 	arch, os := NormalizePlatform(td.RuntimePlatform, isFargateTask || isFargateService)
 	if arch == "" && os == "" {
 		return nil
@@ -474,28 +635,23 @@ func (d *App) verifyRegistryImage(ctx context.Context, image, user, password str
 	return fmt.Errorf("%s:%s for arch=%s os=%s is not found in Registry", image, tag, arch, os)
 }
 
-func (d *App) isFargateService() (bool, error) {
-	p := d.config.ServiceDefinitionPath
-	if p == "" {
-		return false, nil
-	}
-	sv, err := d.LoadServiceDefinition(p)
-	if err != nil {
-		return false, err
+func isFargateService(sv *Service) bool {
+	if sv == nil {
+		return false
 	}
 	if sv.PlatformVersion != nil && *sv.PlatformVersion != "" {
-		return true, nil
+		return true
 	}
 	if sv.LaunchType == types.LaunchTypeFargate {
-		return true, nil
+		return true
 	}
 	for _, s := range sv.CapacityProviderStrategy {
 		name := *s.CapacityProvider
 		if name == "FARGATE_SPOT" || name == "FARGATE" {
-			return true, nil
+			return true
 		}
 	}
-	return false, nil
+	return false
 }
 
 func NormalizePlatform(p *types.RuntimePlatform, isFargate bool) (arch, os string) {
@@ -536,56 +692,64 @@ func (d *App) verifyImage(ctx context.Context, image string) error {
 }
 
 func (d *App) verifyContainer(ctx context.Context, c *types.ContainerDefinition, td *TaskDefinitionInput) error {
+	var errs verifyErrors
 	image := aws.ToString(c.Image)
-	name := fmt.Sprintf("Image[%s]", image)
-	err := verifyResource(ctx, name, func(ctx context.Context) error {
-		return d.verifyImage(ctx, image)
-	})
-	if err != nil {
-		return err
+	targets := []verifyTarget{
+		{
+			name: fmt.Sprintf("Image[%s]", image),
+			fn: func(ctx context.Context) error {
+				return d.verifyImage(ctx, image)
+			},
+		},
 	}
 	for i, secret := range c.Secrets {
+		secret := secret
 		name := aws.ToString(secret.Name)
 		if name == "" {
-			return fmt.Errorf("secrets[%d] name is missing", i)
+			errs = append(errs, fmt.Errorf("secrets[%d] name is missing", i))
+			continue
 		}
 		valueFrom := aws.ToString(secret.ValueFrom)
 		if valueFrom == "" {
-			return fmt.Errorf("secrets[%d] %s valueFrom is missing", i, name)
-		}
-		if err := verifyResource(ctx, fmt.Sprintf("Secret %s[%s]", name, valueFrom), func(ctx context.Context) error {
-			return d.verifier.existsSecretValue(ctx, *secret.ValueFrom)
-		}); err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("secrets[%d] %s valueFrom is missing", i, name))
+			continue
 		}
+		targets = append(targets, verifyTarget{
+			name: fmt.Sprintf("Secret %s[%s]", name, valueFrom),
+			fn: func(ctx context.Context) error {
+				return d.verifier.existsSecretValue(ctx, valueFrom)
+			},
+		})
 	}
 	if c.LogConfiguration != nil && c.LogConfiguration.LogDriver == types.LogDriverAwslogs {
-		name := fmt.Sprintf("LogConfiguration[%s]", map2str(c.LogConfiguration.Options))
-		err := verifyResource(ctx, name, func(ctx context.Context) error {
-			return d.verifyLogConfiguration(ctx, c)
+		targets = append(targets, verifyTarget{
+			name: fmt.Sprintf("LogConfiguration[%s]", map2str(c.LogConfiguration.Options)),
+			fn: func(ctx context.Context) error {
+				return d.verifyLogConfiguration(ctx, c)
+			},
 		})
-		if err != nil {
-			return err
-		}
 	}
 	for _, envFile := range c.EnvironmentFiles {
-		name := fmt.Sprintf("EnvironmentFile[%s %s]", envFile.Type, aws.ToString(envFile.Value))
-		if err := verifyResource(ctx, name, func(ctx context.Context) error {
-			return d.verifier.existsEnvironmentFile(ctx, envFile)
-		}); err != nil {
-			return err
-		}
+		envFile := envFile
+		targets = append(targets, verifyTarget{
+			name: fmt.Sprintf("EnvironmentFile[%s %s]", envFile.Type, aws.ToString(envFile.Value)),
+			fn: func(ctx context.Context) error {
+				return d.verifier.existsEnvironmentFile(ctx, envFile)
+			},
+		})
 	}
 
 	if td.NetworkMode == types.NetworkModeAwsvpc {
 		for _, pm := range c.PortMappings {
 			if pm.HostPort != nil && aws.ToInt32(pm.ContainerPort) != aws.ToInt32(pm.HostPort) {
-				return fmt.Errorf("hostPort must be same as containerPort for awsvpc networkMode")
+				errs = append(errs, fmt.Errorf("hostPort must be same as containerPort for awsvpc networkMode"))
+				break
 			}
 		}
 	}
 
-	return nil
+	errs = append(errs, verifyResources(ctx, targets)...)
+	return errs.orNil()
 }
 
 func (d *App) verifyLogConfiguration(ctx context.Context, c *types.ContainerDefinition) error {
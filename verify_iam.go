@@ -0,0 +1,226 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamTypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+)
+
+// ConfigVerify represents configurations for verify.
+type ConfigVerify struct {
+	TaskRolePermissions []ConfigIAMPermission `yaml:"task_role_permissions,omitempty" json:"task_role_permissions,omitempty"`
+}
+
+// ConfigIAMPermission represents IAM actions required on resources.
+// When Resources is empty, the actions are checked on "*".
+type ConfigIAMPermission struct {
+	Actions   []string `yaml:"actions" json:"actions"`
+	Resources []string `yaml:"resources,omitempty" json:"resources,omitempty"`
+}
+
+type iamPermission struct {
+	Action   string
+	Resource string
+}
+
+type iamPermissions []iamPermission
+
+func (ps *iamPermissions) add(resource string, actions ...string) {
+	for _, action := range actions {
+		*ps = append(*ps, iamPermission{Action: action, Resource: resource})
+	}
+}
+
+// byResource returns unique actions grouped by resources in sorted order.
+func (ps iamPermissions) byResource() ([]string, map[string][]string) {
+	m := make(map[string][]string)
+	seen := make(map[iamPermission]struct{})
+	for _, p := range ps {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		m[p.Resource] = append(m[p.Resource], p.Action)
+	}
+	resources := make([]string, 0, len(m))
+	for r, actions := range m {
+		sort.Strings(actions)
+		resources = append(resources, r)
+	}
+	sort.Strings(resources)
+	return resources, m
+}
+
+var ecrPullActions = []string{
+	"ecr:BatchCheckLayerAvailability",
+	"ecr:GetDownloadUrlForLayer",
+	"ecr:BatchGetImage",
+}
+
+// executionRolePermissions returns permissions which the task execution role requires
+// to pull images, read secrets and environment files, and write logs.
+// roleArn is used to complete the partition and the account id of the resources.
+func executionRolePermissions(td *TaskDefinitionInput, roleArn, region string) (iamPermissions, error) {
+	ra, err := arn.Parse(roleArn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse role arn:%s %w", roleArn, err)
+	}
+	var ps iamPermissions
+	for _, c := range td.ContainerDefinitions {
+		image := aws.ToString(c.Image)
+		if m := ecrImageURLRegex.FindStringSubmatch(image); m != nil {
+			ps.add("*", "ecr:GetAuthorizationToken")
+			ps.add(ecrRepositoryArn(ra.Partition, image, m[2], m[1]), ecrPullActions...)
+		}
+		if rc := c.RepositoryCredentials; rc != nil && rc.CredentialsParameter != nil {
+			ps.add(secretResourceArn(aws.ToString(rc.CredentialsParameter), ra.Partition, region, ra.AccountID), "secretsmanager:GetSecretValue")
+		}
+		for _, s := range c.Secrets {
+			from := aws.ToString(s.ValueFrom)
+			resource := secretResourceArn(from, ra.Partition, region, ra.AccountID)
+			if strings.Contains(resource, ":secretsmanager:") {
+				ps.add(resource, "secretsmanager:GetSecretValue")
+			} else {
+				ps.add(resource, "ssm:GetParameters")
+			}
+		}
+		for _, f := range c.EnvironmentFiles {
+			if f.Type != types.EnvironmentFileTypeS3 {
+				continue
+			}
+			a, err := arn.Parse(aws.ToString(f.Value))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse environment file arn:%s %w", aws.ToString(f.Value), err)
+			}
+			ps.add(a.String(), "s3:GetObject")
+			ps.add(fmt.Sprintf("arn:%s:s3:::%s", a.Partition, strings.SplitN(a.Resource, "/", 2)[0]), "s3:GetBucketLocation")
+		}
+		if lc := c.LogConfiguration; lc != nil && lc.LogDriver == types.LogDriverAwslogs {
+			group, logRegion := lc.Options["awslogs-group"], lc.Options["awslogs-region"]
+			if group == "" {
+				continue
+			}
+			if logRegion == "" {
+				logRegion = region
+			}
+			resource := fmt.Sprintf("arn:%s:logs:%s:%s:log-group:%s:*", ra.Partition, logRegion, ra.AccountID, group)
+			ps.add(resource, "logs:CreateLogStream", "logs:PutLogEvents")
+			if lc.Options["awslogs-create-group"] == "true" {
+				ps.add(resource, "logs:CreateLogGroup")
+			}
+		}
+	}
+	return ps, nil
+}
+
+func ecrRepositoryArn(partition, image, region, accountID string) string {
+	repo := image[strings.Index(image, "/")+1:]
+	if i := strings.Index(repo, "@"); i >= 0 {
+		repo = repo[:i]
+	} else if i := strings.LastIndex(repo, ":"); i >= 0 {
+		repo = repo[:i]
+	}
+	return fmt.Sprintf("arn:%s:ecr:%s:%s:repository/%s", partition, region, accountID, repo)
+}
+
+// secretResourceArn returns an ARN of the secret or the parameter referred by valueFrom.
+func secretResourceArn(from, partition, region, accountID string) string {
+	if strings.HasPrefix(from, "arn:") {
+		part := strings.Split(from, ":")
+		if len(part) >= 7 && part[2] == "secretsmanager" {
+			// Truncate additional params in secretsmanager Arn.
+			return strings.Join(part[0:7], ":")
+		}
+		return from
+	}
+	return fmt.Sprintf("arn:%s:ssm:%s:%s:parameter/%s", partition, region, accountID, strings.TrimPrefix(from, "/"))
+}
+
+// taskRolePermissions returns permissions which the task role requires declared in the config.
+func taskRolePermissions(c *ConfigVerify) iamPermissions {
+	var ps iamPermissions
+	if c == nil {
+		return ps
+	}
+	for _, p := range c.TaskRolePermissions {
+		resources := p.Resources
+		if len(resources) == 0 {
+			resources = []string{"*"}
+		}
+		for _, r := range resources {
+			ps.add(r, p.Actions...)
+		}
+	}
+	return ps
+}
+
+func (d *App) verifyExecutionRolePermissions(ctx context.Context, roleArn string) error {
+	ps, err := executionRolePermissions(d.verifier.td, roleArn, d.config.Region)
+	if err != nil {
+		return err
+	}
+	return d.verifyRolePermissions(ctx, roleArn, ps)
+}
+
+func (d *App) verifyTaskRolePermissions(ctx context.Context, roleArn string) error {
+	return d.verifyRolePermissions(ctx, roleArn, taskRolePermissions(d.config.Verify))
+}
+
+// verifyRolePermissions simulates the role's policies and reports missing actions per resource.
+func (d *App) verifyRolePermissions(ctx context.Context, roleArn string, ps iamPermissions) error {
+	if !d.verifier.opt.SimulatePolicy {
+		return ErrSkipVerify(fmt.Sprintf("simulating policies of %s", roleArn))
+	}
+	if len(ps) == 0 {
+		return ErrSkipVerify("no permissions to simulate")
+	}
+	resources, actions := ps.byResource()
+	var missing []string
+	for _, resource := range resources {
+		denied, err := d.simulatePrincipalPolicy(ctx, roleArn, resource, actions[resource])
+		if err != nil {
+			var ae smithy.APIError
+			if errors.As(err, &ae) && ae.ErrorCode() == "AccessDenied" {
+				return ErrSkipVerify(fmt.Sprintf("simulating policies of %s is not allowed: %s", roleArn, ae.ErrorMessage()))
+			}
+			return fmt.Errorf("failed to simulate policies of %s: %w", roleArn, err)
+		}
+		if len(denied) > 0 {
+			missing = append(missing, fmt.Sprintf("%s on %s", strings.Join(denied, ","), resource))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing permissions: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (d *App) simulatePrincipalPolicy(ctx context.Context, roleArn, resource string, actions []string) ([]string, error) {
+	var denied []string
+	p := iam.NewSimulatePrincipalPolicyPaginator(d.iam, &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(roleArn),
+		ActionNames:     actions,
+		ResourceArns:    []string{resource},
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range out.EvaluationResults {
+			if r.EvalDecision != iamTypes.PolicyEvaluationDecisionTypeAllowed {
+				denied = append(denied, aws.ToString(r.EvalActionName))
+			}
+		}
+	}
+	return denied, nil
+}
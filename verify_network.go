@@ -0,0 +1,241 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2Types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/samber/lo"
+)
+
+// verifyNetworkConfiguration verifies subnets and security groups in the awsvpcConfiguration.
+func (d *App) verifyNetworkConfiguration(ctx context.Context, sv *Service) error {
+	vc := sv.NetworkConfiguration.AwsvpcConfiguration
+	if len(vc.Subnets) == 0 {
+		return errors.New("awsvpcConfiguration.subnets is empty")
+	}
+
+	subnets, err := d.describeSubnets(ctx, vc.Subnets)
+	if err != nil {
+		return err
+	}
+	vpcs := lo.Uniq(lo.Map(subnets, func(s ec2Types.Subnet, _ int) string {
+		return aws.ToString(s.VpcId)
+	}))
+	if len(vpcs) > 1 {
+		return fmt.Errorf("subnets belong to multiple VPCs: %s", strings.Join(vpcs, ","))
+	}
+	vpcID := vpcs[0]
+
+	var errs verifyErrors
+	var sgs []ec2Types.SecurityGroup
+	if len(vc.SecurityGroups) > 0 {
+		out, err := d.verifier.ec2.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+			GroupIds: vc.SecurityGroups,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe security groups %s: %w", strings.Join(vc.SecurityGroups, ","), err)
+		}
+		sgs = out.SecurityGroups
+		for _, sg := range sgs {
+			if v := aws.ToString(sg.VpcId); v != vpcID {
+				errs = append(errs, fmt.Errorf("security group %s belongs to %s, but subnets belong to %s", aws.ToString(sg.GroupId), v, vpcID))
+			}
+		}
+	}
+
+	for _, lb := range sv.LoadBalancers {
+		if err := d.verifyLoadBalancerNetwork(ctx, lb, vpcID, sgs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := d.verifyAssignPublicIp(ctx, vc.AssignPublicIp, subnets); err != nil {
+		errs = append(errs, err)
+	}
+
+	if isFargateService(sv) && sv.DesiredCount != nil {
+		desired := aws.ToInt32(sv.DesiredCount)
+		free := lo.SumBy(subnets, func(s ec2Types.Subnet) int32 {
+			return aws.ToInt32(s.AvailableIpAddressCount)
+		})
+		if free < desired {
+			errs = append(errs, fmt.Errorf("subnets have only %d free IP addresses for the desired count %d", free, desired))
+		}
+	}
+	return errs.orNil()
+}
+
+func (d *App) describeSubnets(ctx context.Context, ids []string) ([]ec2Types.Subnet, error) {
+	out, err := d.verifier.ec2.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: ids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe subnets %s: %w", strings.Join(ids, ","), err)
+	}
+	if len(out.Subnets) != len(ids) {
+		return nil, ErrNotFound(fmt.Sprintf("subnets %s are not found", strings.Join(ids, ",")))
+	}
+	return out.Subnets, nil
+}
+
+// verifyLoadBalancerNetwork verifies that the target group belongs to the VPC and
+// the security groups allow the container port from the load balancer.
+func (d *App) verifyLoadBalancerNetwork(ctx context.Context, lb types.LoadBalancer, vpcID string, sgs []ec2Types.SecurityGroup) error {
+	if lb.TargetGroupArn == nil {
+		return nil
+	}
+	tgArn := aws.ToString(lb.TargetGroupArn)
+	out, err := d.elbv2.DescribeTargetGroups(ctx, &elasticloadbalancingv2.DescribeTargetGroupsInput{
+		TargetGroupArns: []string{tgArn},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe target group %s: %w", tgArn, err)
+	} else if len(out.TargetGroups) == 0 {
+		return ErrNotFound(fmt.Sprintf("target group %s is not found", tgArn))
+	}
+	tg := out.TargetGroups[0]
+	if v := aws.ToString(tg.VpcId); v != vpcID {
+		return fmt.Errorf("target group %s belongs to %s, but subnets belong to %s", tgArn, v, vpcID)
+	}
+	if len(sgs) == 0 {
+		// the default security group of the VPC is applied, so ingress rules are not verified.
+		return nil
+	}
+	if len(tg.LoadBalancerArns) == 0 {
+		return nil
+	}
+	lbs, err := d.elbv2.DescribeLoadBalancers(ctx, &elasticloadbalancingv2.DescribeLoadBalancersInput{
+		LoadBalancerArns: tg.LoadBalancerArns,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe load balancers %s: %w", strings.Join(tg.LoadBalancerArns, ","), err)
+	}
+	port := aws.ToInt32(lb.ContainerPort)
+	for _, l := range lbs.LoadBalancers {
+		if len(l.SecurityGroups) == 0 {
+			continue
+		}
+		var cidrs []netip.Prefix
+		if ids := lo.FilterMap(l.AvailabilityZones, func(az elbv2Types.AvailabilityZone, _ int) (string, bool) {
+			return aws.ToString(az.SubnetId), az.SubnetId != nil
+		}); len(ids) > 0 {
+			subnets, err := d.describeSubnets(ctx, ids)
+			if err != nil {
+				return err
+			}
+			for _, s := range subnets {
+				if p, err := netip.ParsePrefix(aws.ToString(s.CidrBlock)); err == nil {
+					cidrs = append(cidrs, p)
+				}
+			}
+		}
+		if !securityGroupsAllowIngress(sgs, l.SecurityGroups, cidrs, port) {
+			return fmt.Errorf("security groups do not allow port %d from the security groups %s or the subnets of %s", port, strings.Join(l.SecurityGroups, ","), aws.ToString(l.LoadBalancerName))
+		}
+	}
+	return nil
+}
+
+// securityGroupsAllowIngress reports whether the security groups allow TCP ingress on the port
+// from any of the source security groups, or from CIDR ranges covering all of the source CIDRs.
+// When no source CIDRs are given, only 0.0.0.0/0 is accepted as a CIDR range.
+func securityGroupsAllowIngress(sgs []ec2Types.SecurityGroup, sourceSGs []string, sourceCIDRs []netip.Prefix, port int32) bool {
+	if len(sourceCIDRs) == 0 {
+		sourceCIDRs = []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")}
+	}
+	covered := make([]bool, len(sourceCIDRs))
+	for _, sg := range sgs {
+		for _, p := range sg.IpPermissions {
+			proto := aws.ToString(p.IpProtocol)
+			if proto != "-1" {
+				if proto != "tcp" && proto != "6" {
+					continue
+				}
+				if port < aws.ToInt32(p.FromPort) || aws.ToInt32(p.ToPort) < port {
+					continue
+				}
+			}
+			for _, pair := range p.UserIdGroupPairs {
+				if lo.Contains(sourceSGs, aws.ToString(pair.GroupId)) {
+					return true
+				}
+			}
+			for _, r := range p.IpRanges {
+				allowed, err := netip.ParsePrefix(aws.ToString(r.CidrIp))
+				if err != nil {
+					continue
+				}
+				for i, src := range sourceCIDRs {
+					if allowed.Bits() <= src.Bits() && allowed.Contains(src.Masked().Addr()) {
+						covered[i] = true
+					}
+				}
+			}
+		}
+	}
+	return !lo.Contains(covered, false)
+}
+
+// verifyAssignPublicIp verifies assignPublicIp is consistent with the routing of the subnets.
+func (d *App) verifyAssignPublicIp(ctx context.Context, assign types.AssignPublicIp, subnets []ec2Types.Subnet) error {
+	var errs verifyErrors
+	for _, s := range subnets {
+		id := aws.ToString(s.SubnetId)
+		rt, err := d.findRouteTable(ctx, id, aws.ToString(s.VpcId))
+		if err != nil {
+			return err
+		}
+		public := isPublicRouteTable(rt)
+		switch {
+		case assign == types.AssignPublicIpEnabled && !public:
+			errs = append(errs, fmt.Errorf("assignPublicIp is ENABLED, but subnet %s has no route to an internet gateway", id))
+		case assign != types.AssignPublicIpEnabled && public:
+			d.Log("[WARNING] assignPublicIp is DISABLED, but subnet %s routes to an internet gateway. Tasks cannot reach the internet without VPC endpoints.", id)
+		}
+	}
+	return errs.orNil()
+}
+
+// findRouteTable finds the route table associated with the subnet, or the main route table of the VPC.
+func (d *App) findRouteTable(ctx context.Context, subnetID, vpcID string) (*ec2Types.RouteTable, error) {
+	filters := [][]ec2Types.Filter{
+		{
+			{Name: aws.String("association.subnet-id"), Values: []string{subnetID}},
+		},
+		{
+			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+			{Name: aws.String("association.main"), Values: []string{"true"}},
+		},
+	}
+	for _, f := range filters {
+		out, err := d.verifier.ec2.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+			Filters: f,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe route tables for %s: %w", subnetID, err)
+		}
+		if len(out.RouteTables) > 0 {
+			return &out.RouteTables[0], nil
+		}
+	}
+	return nil, ErrNotFound(fmt.Sprintf("route table for subnet %s is not found", subnetID))
+}
+
+// isPublicRouteTable reports whether the route table has a default route to an internet gateway.
+func isPublicRouteTable(rt *ec2Types.RouteTable) bool {
+	for _, r := range rt.Routes {
+		if aws.ToString(r.DestinationCidrBlock) == "0.0.0.0/0" && strings.HasPrefix(aws.ToString(r.GatewayId), "igw-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+type VerifyStatus string
+
+const (
+	VerifyStatusOK   VerifyStatus = "OK"
+	VerifyStatusSkip VerifyStatus = "SKIP"
+	VerifyStatusNG   VerifyStatus = "NG"
+)
+
+// VerifyResult represents a result of verifying a resource and its nested resources.
+type VerifyResult struct {
+	Name      string          `json:"name"`
+	Status    VerifyStatus    `json:"status"`
+	Message   string          `json:"message,omitempty"`
+	Cached    bool            `json:"cached,omitempty"`
+	Resources []*VerifyResult `json:"resources,omitempty"`
+
+	level int
+	mu    sync.Mutex
+}
+
+func newVerifyResultRoot() *VerifyResult {
+	return &VerifyResult{}
+}
+
+func (r *VerifyResult) add(name string) *VerifyResult {
+	child := &VerifyResult{
+		Name:  name,
+		level: r.level + 1,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Resources = append(r.Resources, child)
+	return child
+}
+
+// walk calls fn for the result and all of its nested results with their paths.
+func (r *VerifyResult) walk(path []string, fn func(path []string, r *VerifyResult)) {
+	path = append(path, r.Name)
+	fn(path, r)
+	for _, c := range r.Resources {
+		c.walk(path, fn)
+	}
+}
+
+type verifyResultKey struct{}
+
+func withVerifyResult(ctx context.Context, r *VerifyResult) context.Context {
+	return context.WithValue(ctx, verifyResultKey{}, r)
+}
+
+func verifyResultFromContext(ctx context.Context) *VerifyResult {
+	if r, ok := ctx.Value(verifyResultKey{}).(*VerifyResult); ok {
+		return r
+	}
+	return verifyState.root
+}
+
+// verifyErrors is returned by a resource that has failed checks or failed nested resources.
+type verifyErrors []error
+
+func (errs verifyErrors) Error() string {
+	ss := make([]string, 0, len(errs))
+	for _, err := range errs {
+		ss = append(ss, err.Error())
+	}
+	return strings.Join(ss, ", ")
+}
+
+func (errs verifyErrors) orNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+type VerifySummary struct {
+	OK   int `json:"ok"`
+	Skip int `json:"skip"`
+	NG   int `json:"ng"`
+}
+
+// VerifyReport represents a report of Verify.
+type VerifyReport struct {
+	Status    VerifyStatus    `json:"status"`
+	Summary   VerifySummary   `json:"summary"`
+	Resources []*VerifyResult `json:"resources"`
+}
+
+func newVerifyReport(root *VerifyResult) *VerifyReport {
+	report := &VerifyReport{
+		Status:    VerifyStatusOK,
+		Resources: root.Resources,
+	}
+	for _, r := range root.Resources {
+		r.walk(nil, func(_ []string, r *VerifyResult) {
+			switch r.Status {
+			case VerifyStatusOK:
+				report.Summary.OK++
+			case VerifyStatusSkip:
+				report.Summary.Skip++
+			case VerifyStatusNG:
+				report.Summary.NG++
+				report.Status = VerifyStatusNG
+			}
+		})
+	}
+	return report
+}
+
+func (r *VerifyReport) OutputJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal verify report: %w", err)
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func (r *VerifyReport) OutputJUnit(w io.Writer) error {
+	suites := junitTestSuites{Name: "ecspresso verify"}
+	for _, res := range r.Resources {
+		suite := junitTestSuite{Name: res.Name}
+		res.walk(nil, func(path []string, r *VerifyResult) {
+			tc := junitTestCase{
+				Name:      strings.Join(path, "/"),
+				ClassName: res.Name,
+			}
+			switch r.Status {
+			case VerifyStatusNG:
+				tc.Failure = &junitMessage{Message: r.Message}
+				suite.Failures++
+			case VerifyStatusSkip:
+				tc.Skipped = &junitMessage{Message: r.Message}
+				suite.Skipped++
+			}
+			suite.Tests++
+			suite.TestCases = append(suite.TestCases, tc)
+		})
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+	b, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal verify report: %w", err)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
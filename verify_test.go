@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"net/netip"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/fatih/color"
+	"github.com/google/go-cmp/cmp"
 	"github.com/kayac/ecspresso/v2"
 )
 
@@ -268,3 +273,286 @@ func TestVerifierIsAssumed(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyReport(t *testing.T) {
+	color.NoColor = true
+	ecspresso.InitVerifyState(false)
+	ctx := context.TODO()
+	extractStdout(t, func() {
+		err := ecspresso.VerifyResource(ctx, "parent", func(ctx context.Context) error {
+			var errs []error
+			for _, r := range []struct {
+				name string
+				err  error
+			}{
+				{"ok", nil},
+				{"ng", errors.New("XXX")},
+				{"skip", ecspresso.ErrSkipVerify("hello")},
+			} {
+				r := r
+				if err := ecspresso.VerifyResource(ctx, r.name, func(_ context.Context) error {
+					return r.err
+				}); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if len(errs) > 0 {
+				return errs[0]
+			}
+			return nil
+		})
+		if err == nil {
+			t.Error("error must be returned for parent resource")
+		}
+		if err := ecspresso.VerifyResource(ctx, "other", func(_ context.Context) error {
+			return nil
+		}); err != nil {
+			t.Error("unexpected error for other resource", err)
+		}
+	})
+
+	report := ecspresso.NewVerifyReport()
+	if report.Status != ecspresso.VerifyStatusNG {
+		t.Errorf("unexpected report status: %s", report.Status)
+	}
+	if s := report.Summary; s.OK != 2 || s.NG != 2 || s.Skip != 1 {
+		t.Errorf("unexpected summary: %#v", s)
+	}
+	if len(report.Resources) != 2 {
+		t.Fatalf("unexpected number of top level resources: %d", len(report.Resources))
+	}
+	parent := report.Resources[0]
+	if parent.Name != "parent" || len(parent.Resources) != 3 {
+		t.Errorf("unexpected parent resource: %#v", parent)
+	}
+	if ng := parent.Resources[1]; ng.Status != ecspresso.VerifyStatusNG || ng.Message != "XXX" {
+		t.Errorf("unexpected ng resource: %#v", ng)
+	}
+
+	var buf bytes.Buffer
+	if err := report.OutputJSON(&buf); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"status": "NG"`)) {
+		t.Errorf("unexpected JSON output: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := report.OutputJUnit(&buf); err != nil {
+		t.Error(err)
+	}
+	for _, s := range []string{
+		`<testsuites name="ecspresso verify" tests="5" failures="2" skipped="1">`,
+		`<testcase name="parent/ng" classname="parent">`,
+		`<failure message="XXX"></failure>`,
+		`<skipped message="hello"></skipped>`,
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(s)) {
+			t.Errorf("JUnit output must contain %s: %s", s, buf.String())
+		}
+	}
+}
+
+func TestVerifyResourcesParallel(t *testing.T) {
+	color.NoColor = true
+	ecspresso.InitVerifyState(true)
+	ecspresso.SetVerifyParallel(4)
+	ctx := context.TODO()
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	calls := map[string]int{}
+	names := []string{"a", "b", "c", "d", "a", "e", "f", "g"}
+	out := extractStdout(t, func() {
+		err := ecspresso.VerifyResource(ctx, "parent", func(ctx context.Context) error {
+			return ecspresso.VerifyResources(ctx, names, func(_ context.Context, name string) error {
+				mu.Lock()
+				calls[name]++
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				if name == "c" {
+					return errors.New("XXX")
+				}
+				return nil
+			})
+		})
+		if err == nil {
+			t.Error("error must be returned for parent resource")
+		}
+	})
+	if maxRunning > 4 {
+		t.Errorf("too many resources verified in parallel: %d", maxRunning)
+	}
+	if calls["a"] != 1 {
+		t.Errorf("cached resource must be verified once: %d", calls["a"])
+	}
+
+	report := ecspresso.NewVerifyReport()
+	parent := report.Resources[0]
+	for i, r := range parent.Resources {
+		if r.Name != names[i] {
+			t.Errorf("unexpected order of resources: %d %s", i, r.Name)
+		}
+	}
+	if s := report.Summary; s.NG != 2 || s.OK != 7 {
+		t.Errorf("unexpected summary: %#v", s)
+	}
+	if !bytes.HasPrefix(out, []byte("  parent\n    a\n    --> [OK]")) {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestExecutionRolePermissions(t *testing.T) {
+	td := &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{
+				Name:  aws.String("app"),
+				Image: aws.String("123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:latest"),
+				Secrets: []types.Secret{
+					{Name: aws.String("FOO"), ValueFrom: aws.String("/app/foo")},
+					{Name: aws.String("BAR"), ValueFrom: aws.String("arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/bar")},
+					{Name: aws.String("BAZ"), ValueFrom: aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:app-AbCdEf:password::")},
+				},
+				EnvironmentFiles: []types.EnvironmentFile{
+					{Type: types.EnvironmentFileTypeS3, Value: aws.String("arn:aws:s3:::bucket/app.env")},
+				},
+				LogConfiguration: &types.LogConfiguration{
+					LogDriver: types.LogDriverAwslogs,
+					Options: map[string]string{
+						"awslogs-group":        "/ecs/app",
+						"awslogs-region":       "ap-northeast-1",
+						"awslogs-create-group": "true",
+					},
+				},
+			},
+			{
+				Name:  aws.String("sidecar"),
+				Image: aws.String("123456789012.dkr.ecr.us-east-1.amazonaws.com/org/sidecar@sha256:abcdef"),
+			},
+			{
+				Name:  aws.String("nginx"),
+				Image: aws.String("nginx:latest"),
+			},
+		},
+	}
+	ps, err := ecspresso.ExecutionRolePermissions(td, "arn:aws:iam::123456789012:role/ecsTaskExecutionRole", "ap-northeast-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"*": {"ecr:GetAuthorizationToken"},
+		"arn:aws:ecr:ap-northeast-1:123456789012:repository/app":               {"ecr:BatchCheckLayerAvailability", "ecr:BatchGetImage", "ecr:GetDownloadUrlForLayer"},
+		"arn:aws:ecr:us-east-1:123456789012:repository/org/sidecar":            {"ecr:BatchCheckLayerAvailability", "ecr:BatchGetImage", "ecr:GetDownloadUrlForLayer"},
+		"arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/foo":            {"ssm:GetParameters"},
+		"arn:aws:ssm:ap-northeast-1:123456789012:parameter/app/bar":            {"ssm:GetParameters"},
+		"arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:app-AbCdEf": {"secretsmanager:GetSecretValue"},
+		"arn:aws:s3:::bucket/app.env":                                          {"s3:GetObject"},
+		"arn:aws:s3:::bucket":                                                  {"s3:GetBucketLocation"},
+		"arn:aws:logs:ap-northeast-1:123456789012:log-group:/ecs/app:*":        {"logs:CreateLogGroup", "logs:CreateLogStream", "logs:PutLogEvents"},
+	}
+	if diff := cmp.Diff(expected, ps); diff != "" {
+		t.Errorf("unexpected permissions: %s", diff)
+	}
+}
+
+func TestTaskRolePermissions(t *testing.T) {
+	ps := ecspresso.TaskRolePermissions(&ecspresso.ConfigVerify{
+		TaskRolePermissions: []ecspresso.ConfigIAMPermission{
+			{Actions: []string{"s3:GetObject", "s3:PutObject"}, Resources: []string{"arn:aws:s3:::bucket/*"}},
+			{Actions: []string{"sqs:SendMessage"}},
+		},
+	})
+	expected := map[string][]string{
+		"arn:aws:s3:::bucket/*": {"s3:GetObject", "s3:PutObject"},
+		"*":                     {"sqs:SendMessage"},
+	}
+	if diff := cmp.Diff(expected, ps); diff != "" {
+		t.Errorf("unexpected permissions: %s", diff)
+	}
+}
+
+func TestSecurityGroupsAllowIngress(t *testing.T) {
+	sgs := []ec2Types.SecurityGroup{
+		{
+			GroupId: aws.String("sg-task"),
+			IpPermissions: []ec2Types.IpPermission{
+				{
+					IpProtocol:       aws.String("tcp"),
+					FromPort:         aws.Int32(8000),
+					ToPort:           aws.Int32(8080),
+					UserIdGroupPairs: []ec2Types.UserIdGroupPair{{GroupId: aws.String("sg-alb")}},
+				},
+				{
+					IpProtocol: aws.String("udp"),
+					FromPort:   aws.Int32(53),
+					ToPort:     aws.Int32(53),
+					IpRanges:   []ec2Types.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+				},
+				{
+					IpProtocol:       aws.String("-1"),
+					UserIdGroupPairs: []ec2Types.UserIdGroupPair{{GroupId: aws.String("sg-internal")}},
+				},
+				{
+					IpProtocol: aws.String("tcp"),
+					FromPort:   aws.Int32(3000),
+					ToPort:     aws.Int32(3000),
+					IpRanges:   []ec2Types.IpRange{{CidrIp: aws.String("10.0.0.0/16")}},
+				},
+			},
+		},
+	}
+	vpcSubnets := []netip.Prefix{netip.MustParsePrefix("10.0.1.0/24"), netip.MustParsePrefix("10.0.2.0/24")}
+	tests := []struct {
+		sources []string
+		cidrs   []netip.Prefix
+		port    int32
+		allow   bool
+	}{
+		{[]string{"sg-alb"}, nil, 8080, true},
+		{[]string{"sg-alb"}, nil, 9000, false},
+		{[]string{"sg-alb"}, nil, 53, false},
+		{[]string{"sg-other"}, nil, 8080, false},
+		{[]string{"sg-internal"}, nil, 9000, true},
+		// any of the load balancer's security groups is enough
+		{[]string{"sg-other", "sg-alb"}, nil, 8080, true},
+		// CIDR ranges covering the subnets of the load balancer
+		{[]string{"sg-other"}, vpcSubnets, 3000, true},
+		{[]string{"sg-other"}, []netip.Prefix{netip.MustParsePrefix("10.1.1.0/24")}, 3000, false},
+		{[]string{"sg-other"}, []netip.Prefix{netip.MustParsePrefix("10.0.1.0/24"), netip.MustParsePrefix("172.16.0.0/24")}, 3000, false},
+		// 10.0.0.0/16 does not cover unknown sources
+		{[]string{"sg-other"}, nil, 3000, false},
+	}
+	for _, tt := range tests {
+		if got := ecspresso.SecurityGroupsAllowIngress(sgs, tt.sources, tt.cidrs, tt.port); got != tt.allow {
+			t.Errorf("%v %v:%d expected %v, got %v", tt.sources, tt.cidrs, tt.port, tt.allow, got)
+		}
+	}
+}
+
+func TestIsPublicRouteTable(t *testing.T) {
+	public := &ec2Types.RouteTable{
+		Routes: []ec2Types.Route{
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-12345678")},
+		},
+	}
+	private := &ec2Types.RouteTable{
+		Routes: []ec2Types.Route{
+			{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")},
+			{DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-12345678")},
+		},
+	}
+	if !ecspresso.IsPublicRouteTable(public) {
+		t.Error("expected public route table")
+	}
+	if ecspresso.IsPublicRouteTable(private) {
+		t.Error("expected private route table")
+	}
+}
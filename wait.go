@@ -35,6 +35,9 @@ func (confirm confirmFunc) wrap(wait waitFunc) waitFunc {
 
 func (d *App) WaitFunc(sv *Service, confirm confirmFunc) (waitFunc, error) {
 	defaultFunc := confirm.wrap(d.WaitServiceStable)
+	if sv != nil && sv.isBlueGreen() {
+		defaultFunc = confirm.wrap(d.WaitServiceBlueGreen)
+	}
 	if sv == nil || sv.DeploymentController == nil {
 		return defaultFunc, nil
 	}
@@ -44,6 +47,8 @@ func (d *App) WaitFunc(sv *Service, confirm confirmFunc) (waitFunc, error) {
 			return d.WaitForCodeDeploy, nil
 		case types.DeploymentControllerTypeEcs:
 			return defaultFunc, nil
+		case types.DeploymentControllerTypeExternal:
+			return d.WaitTaskSetStable, nil
 		default:
 			return nil, fmt.Errorf("unsupported deployment controller type: %s", dc.Type)
 		}
@@ -73,10 +78,13 @@ func (d *App) confirmPrimaryTD(tdArn string) confirmFunc {
 type WaitOption struct {
 }
 
-func (d *App) Wait(ctx context.Context, opt WaitOption) error {
+func (d *App) Wait(ctx context.Context, opt WaitOption) (err error) {
 	ctx, cancel := d.Start(ctx)
 	defer cancel()
 
+	ctx, n := d.startNotification(ctx)
+	defer func() { d.notifyResult(ctx, n, err, eventWaitSucceeded, eventWaitFailed) }()
+
 	d.Log("Waiting for the service stable")
 
 	sv, err := d.DescribeServiceStatus(ctx, 0)
@@ -84,6 +92,7 @@ func (d *App) Wait(ctx context.Context, opt WaitOption) error {
 		return err
 	}
 	d.LogJSON(sv.DeploymentController)
+	recordTaskDefinition(ctx, "", aws.ToString(sv.TaskDefinition))
 	doWait, err := d.WaitFunc(sv, nil)
 	if err != nil {
 		return err
@@ -139,10 +148,29 @@ func (d *App) WaitServiceStable(ctx context.Context, sv *Service) error {
 
 func (d *App) WaitForCodeDeploy(ctx context.Context, sv *Service) error {
 	d.Log("[DEBUG] wait for CodeDeploy")
-	dp, err := d.findDeploymentInfo(ctx)
+	dpID, err := d.findInProgressDeploymentID(ctx)
 	if err != nil {
 		return err
 	}
+	d.Log("Waiting for a deployment successful ID: " + dpID)
+	go d.codeDeployProgressBar(ctx, dpID)
+
+	waiter := codedeploy.NewDeploymentSuccessfulWaiter(d.codedeploy, func(o *codedeploy.DeploymentSuccessfulWaiterOptions) {
+		o.MaxDelay = waiterMaxDelay
+	})
+	return waiter.Wait(
+		ctx,
+		&codedeploy.GetDeploymentInput{DeploymentId: &dpID},
+		d.Timeout(),
+	)
+}
+
+// findInProgressDeploymentID returns the ID of the latest deployment in progress on CodeDeploy.
+func (d *App) findInProgressDeploymentID(ctx context.Context) (string, error) {
+	dp, err := d.findDeploymentInfo(ctx)
+	if err != nil {
+		return "", err
+	}
 	out, err := d.codedeploy.ListDeployments(
 		ctx,
 		&codedeploy.ListDeploymentsInput{
@@ -157,24 +185,12 @@ func (d *App) WaitForCodeDeploy(ctx context.Context, sv *Service) error {
 		},
 	)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if len(out.Deployments) == 0 {
-		return ErrNotFound("No deployments found in progress on CodeDeploy")
+		return "", ErrNotFound("No deployments found in progress on CodeDeploy")
 	}
-
-	dpID := out.Deployments[0]
-	d.Log("Waiting for a deployment successful ID: " + dpID)
-	go d.codeDeployProgressBar(ctx, dpID)
-
-	waiter := codedeploy.NewDeploymentSuccessfulWaiter(d.codedeploy, func(o *codedeploy.DeploymentSuccessfulWaiterOptions) {
-		o.MaxDelay = waiterMaxDelay
-	})
-	return waiter.Wait(
-		ctx,
-		&codedeploy.GetDeploymentInput{DeploymentId: &dpID},
-		d.Timeout(),
-	)
+	return out.Deployments[0], nil
 }
 
 type showState struct {